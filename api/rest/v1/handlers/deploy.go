@@ -2,21 +2,26 @@ package handlers
 
 import (
 	"errors"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	v1 "github.com/ignis-runtime/ignis-wasmtime/api/rest/v1"
 	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/schemas"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/secrets"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
 )
 
 type DeployHandler struct {
-	service services.DeploymentService
+	service   services.DeploymentService
+	listeners services.ListenerService
 }
 
-func NewDeployHandler(service services.DeploymentService) *DeployHandler {
+func NewDeployHandler(service services.DeploymentService, listeners services.ListenerService) *DeployHandler {
 	return &DeployHandler{
-		service: service,
+		service:   service,
+		listeners: listeners,
 	}
 }
 
@@ -34,7 +39,18 @@ func (d *DeployHandler) HandleCreateDeployment(c *gin.Context) error {
 	if err != nil {
 		// Handle specific error types
 		var invalidRuntimeTypeError *services.InvalidRuntimeTypeError
-		if errors.As(err, &invalidRuntimeTypeError) {
+		var invalidEnvError *services.InvalidEnvError
+		var invalidPreopenedDirError *services.InvalidPreopenedDirError
+		var unsupportedOptionError *services.UnsupportedOptionError
+		var invalidWorkspaceModeError *services.InvalidWorkspaceModeError
+		var invalidEgressPolicyError *services.InvalidEgressPolicyError
+		var invalidDeploymentKindError *services.InvalidDeploymentKindError
+		var invalidListenPortError *services.InvalidListenPortError
+		if errors.As(err, &invalidRuntimeTypeError) || errors.As(err, &invalidEnvError) ||
+			errors.As(err, &invalidPreopenedDirError) || errors.As(err, &unsupportedOptionError) ||
+			errors.As(err, &invalidWorkspaceModeError) || errors.As(err, &invalidEgressPolicyError) ||
+			errors.As(err, &invalidDeploymentKindError) || errors.As(err, &invalidListenPortError) ||
+			errors.Is(err, secrets.ErrNoKey) {
 			return v1.APIError{
 				Code: http.StatusBadRequest,
 				Err:  err.Error(),
@@ -48,10 +64,26 @@ func (d *DeployHandler) HandleCreateDeployment(c *gin.Context) error {
 		}
 	}
 
-	// The service layer returns information about whether this was an existing runtime
+	// TCP deployments start accepting connections right away. One whose
+	// port can't be bound is removed again, so it doesn't linger unserved
+	if result.Kind == services.DeploymentKindTCP {
+		if err := d.listeners.Listen(result); err != nil {
+			if id, parseErr := uuid.Parse(result.ID); parseErr == nil {
+				if delErr := d.service.DeleteDeployment(c.Request.Context(), id); delErr != nil {
+					log.Printf("deploy %s: failed to remove after its listener failed: %v", result.ID, delErr)
+				}
+			}
+			return v1.APIError{
+				Code: http.StatusInternalServerError,
+				Err:  "Deployment was not saved because its listener failed: " + err.Error(),
+			}
+		}
+	}
+
+	// The service layer reports whether the file was already stored
 	var msg string
 	if result.IsExisting {
-		msg = "Runtime with same hash already exists"
+		msg = "Successfully deployed, sharing the stored file with the same hash"
 	} else {
 		msg = "Successfully deployed"
 	}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	v1 "github.com/ignis-runtime/ignis-wasmtime/api/rest/v1"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/schemas"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
+	"gorm.io/gorm"
+)
+
+// defaultLogLimit is the number of entries returned when no limit is given
+const defaultLogLimit = 50
+
+type LogHandlers struct {
+	deploymentService services.DeploymentService
+	runService        services.RunService
+}
+
+func NewLogHandlers(runService services.RunService, deploymentService services.DeploymentService) *LogHandlers {
+	return &LogHandlers{
+		deploymentService: deploymentService,
+		runService:        runService,
+	}
+}
+
+func (h *LogHandlers) HandleGetDeploymentLogs(c *gin.Context) error {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		return v1.APIError{Code: http.StatusBadRequest, Err: "invalid UUID"}
+	}
+
+	limit := defaultLogLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return v1.APIError{Code: http.StatusBadRequest, Err: "limit must be a positive integer"}
+		}
+	}
+
+	if _, err := h.deploymentService.GetDeploymentByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return v1.APIError{Code: http.StatusNotFound, Err: "deployment not found"}
+		}
+		return v1.APIError{Code: http.StatusInternalServerError, Err: "Failed to retrieve deployment"}
+	}
+
+	entries := h.runService.RecentLogs(id, limit)
+	result := make([]schemas.LogEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = schemas.LogEntry{
+			Time:           entry.Time,
+			RequestID:      entry.RequestID,
+			Stderr:         entry.Stderr,
+			Truncated:      entry.Truncated,
+			RecordsDropped: entry.RecordsDropped,
+		}
+		for _, record := range entry.Records {
+			result[i].Records = append(result[i].Records, schemas.LogRecord{
+				Time:    record.Time,
+				Level:   record.Level,
+				Message: record.Message,
+				Fields:  record.Fields,
+			})
+		}
+	}
+
+	return v1.APIResponse{
+		Code: http.StatusOK,
+		Msg:  "Successfully retrieved logs",
+		Data: result,
+	}
+}
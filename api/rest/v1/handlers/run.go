@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"io"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	v1 "github.com/ignis-runtime/ignis-wasmtime/api/rest/v1"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
 	"github.com/ignis-runtime/ignis-wasmtime/types"
 )
@@ -58,6 +60,16 @@ func (s *RunHandlers) HandleWasmRequest(c *gin.Context) error {
 		},
 	)
 	if err != nil {
+		if errors.Is(err, services.ErrTCPDeployment) {
+			return v1.APIError{Code: http.StatusBadRequest, Err: err.Error()}
+		}
+		if errors.Is(err, runtime.ErrExecutionTimeout) {
+			return v1.APIError{Code: http.StatusGatewayTimeout, Err: err.Error()}
+		}
+		var limitErr *runtime.LimitError
+		if errors.Is(err, runtime.ErrFuelExhausted) || errors.Is(err, runtime.ErrInstancePoolExhausted) || errors.As(err, &limitErr) {
+			return v1.APIError{Code: http.StatusServiceUnavailable, Err: err.Error()}
+		}
 		return v1.APIError{Code: http.StatusInternalServerError, Err: err.Error()}
 	}
 
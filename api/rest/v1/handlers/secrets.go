@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	v1 "github.com/ignis-runtime/ignis-wasmtime/api/rest/v1"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/schemas"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/secrets"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
+	"gorm.io/gorm"
+)
+
+type SecretHandlers struct {
+	deploymentService services.DeploymentService
+}
+
+func NewSecretHandlers(deploymentService services.DeploymentService) *SecretHandlers {
+	return &SecretHandlers{
+		deploymentService: deploymentService,
+	}
+}
+
+// deployment looks up the deployment named in the path
+func (h *SecretHandlers) deployment(c *gin.Context) (uuid.UUID, *schemas.DeployResponse, error) {
+	id, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		return uuid.Nil, nil, v1.APIError{Code: http.StatusBadRequest, Err: "invalid UUID"}
+	}
+	deployment, err := h.deploymentService.GetDeploymentByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, nil, v1.APIError{Code: http.StatusNotFound, Err: "deployment not found"}
+		}
+		return uuid.Nil, nil, v1.APIError{Code: http.StatusInternalServerError, Err: "Failed to retrieve deployment"}
+	}
+	return id, deployment, nil
+}
+
+func (h *SecretHandlers) HandleListSecrets(c *gin.Context) error {
+	_, deployment, err := h.deployment(c)
+	if err != nil {
+		return err
+	}
+	return v1.APIResponse{
+		Code: http.StatusOK,
+		Msg:  "Successfully retrieved secrets",
+		Data: deployment.HostSecretKeys,
+	}
+}
+
+func (h *SecretHandlers) HandleSetSecret(c *gin.Context) error {
+	id, _, err := h.deployment(c)
+	if err != nil {
+		return err
+	}
+	var req schemas.SecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return v1.APIError{Code: http.StatusBadRequest, Err: "Bad Request"}
+	}
+
+	err = h.deploymentService.SetHostSecret(c.Request.Context(), id, c.Param("name"), req.Value)
+	if err != nil {
+		var invalidSecretError *services.InvalidSecretError
+		if errors.As(err, &invalidSecretError) || errors.Is(err, secrets.ErrNoKey) {
+			return v1.APIError{Code: http.StatusBadRequest, Err: err.Error()}
+		}
+		return v1.APIError{Code: http.StatusInternalServerError, Err: "Failed to store secret"}
+	}
+	return v1.APIResponse{
+		Code: http.StatusOK,
+		Msg:  "Successfully stored secret",
+	}
+}
+
+func (h *SecretHandlers) HandleDeleteSecret(c *gin.Context) error {
+	id, _, err := h.deployment(c)
+	if err != nil {
+		return err
+	}
+	existed, err := h.deploymentService.DeleteHostSecret(c.Request.Context(), id, c.Param("name"))
+	if err != nil {
+		return v1.APIError{Code: http.StatusInternalServerError, Err: "Failed to delete secret"}
+	}
+	if !existed {
+		return v1.APIError{Code: http.StatusNotFound, Err: "secret not found"}
+	}
+	return v1.APIResponse{
+		Code: http.StatusOK,
+		Msg:  "Successfully deleted secret",
+	}
+}
@@ -5,8 +5,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/logs"
 )
 
+// RequestIDHeader carries the ID used to correlate a request with its logs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-provided request IDs
+const maxRequestIDLength = 128
+
 func UUIDValidator() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		runtimeID := c.Param("uuid")
@@ -23,3 +30,18 @@ func UUIDValidator() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequestID propagates the client's X-Request-ID, or assigns a new one, to the
+// request context and the response headers
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(logs.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
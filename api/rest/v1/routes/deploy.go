@@ -14,14 +14,32 @@ import (
 // @Produce json
 // @Param runtime_type formData string true "Runtime type (js or wasm)" Enums(js, wasm)
 // @Param file formData file true "Runtime file to deploy"
-// @Param preopened_dir formData string false "Preopened directory for WASI"
-// @Param args[] formData string false "Arguments to pass to the runtime"
+// @Param preopened_dir formData string false "Directory relative to the server's sandbox root, preopened for WASI (wasm only)"
+// @Param args formData []string false "Arguments passed to the module after its program name (wasm only)" collectionFormat(multi)
+// @Param timeout_ms formData int false "Per-execution wall-clock limit in milliseconds (0 uses the server default)"
+// @Param fuel_budget formData int false "Per-execution fuel budget (0 uses the server default)"
+// @Param max_memory_bytes formData int false "Maximum linear memory in bytes (0 uses the server default)"
+// @Param max_table_elements formData int false "Maximum elements per table (0 uses the server default)"
+// @Param max_instances formData int false "Maximum instances per store (0 uses the server default)"
+// @Param max_memories formData int false "Maximum linear memories per store (0 uses the server default)"
+// @Param env formData []string false "Environment variables for the guest, as KEY=VALUE" collectionFormat(multi)
+// @Param secrets formData []string false "Secret environment variables, as KEY=VALUE; stored encrypted and never returned" collectionFormat(multi)
+// @Param workspace_mode formData string false "Lifetime of the workspace mounted at /data, ephemeral by default" Enums(none, ephemeral, persistent)
+// @Param workspace_quota formData int false "Maximum size of the workspace in bytes (0 uses the server default)"
+// @Param egress_hosts formData []string false "Hosts guests may connect to, e.g. api.example.com or *.example.com" collectionFormat(multi)
+// @Param egress_cidrs formData []string false "Networks guests may connect to; the only way to reach private addresses" collectionFormat(multi)
+// @Param egress_ports formData []int false "Ports guests may connect to; empty allows every port" collectionFormat(multi)
+// @Param http_timeout_ms formData int false "Time limit of each outbound HTTP request in milliseconds (0 uses the server default)"
+// @Param http_max_body formData int false "Maximum outbound HTTP response body in bytes (0 uses the server default)"
+// @Param http_max_redirects formData int false "Redirects followed by outbound HTTP requests (0 uses the server default)"
+// @Param kind formData string false "How the deployment is invoked: through the run endpoint, or by connections to listen_port (wasm only)" Enums(http, tcp)
+// @Param listen_port formData int false "Host port accepting connections, within the server's TCP port range (tcp only)"
 // @Success 200 {object} v1.APIResponse{data=schemas.DeployResponse}
 // @Failure 400 {object} v1.APIError
 // @Failure 500 {object} v1.APIError
 // @Router /deploy [post]
-func handleCreateDeployment(deployService services.DeploymentService, router gin.IRoutes) {
-	deployHandler := handlers.NewDeployHandler(deployService)
+func handleCreateDeployment(deployService services.DeploymentService, listeners services.ListenerService, router gin.IRoutes) {
+	deployHandler := handlers.NewDeployHandler(deployService, listeners)
 	router.POST("/deploy", v1.ErrorHandler(deployHandler.HandleCreateDeployment))
 }
 
@@ -33,12 +51,12 @@ func handleCreateDeployment(deployService services.DeploymentService, router gin
 // @Success 200 {object} v1.APIResponse{data=[]schemas.DeployResponse}
 // @Failure 500 {object} v1.APIError
 // @Router /deploy [get]
-func handleListDeployments(deployService services.DeploymentService, router gin.IRoutes) {
-	deployHandler := handlers.NewDeployHandler(deployService)
+func handleListDeployments(deployService services.DeploymentService, listeners services.ListenerService, router gin.IRoutes) {
+	deployHandler := handlers.NewDeployHandler(deployService, listeners)
 	router.GET("/deploy", v1.ErrorHandler(deployHandler.HandleListDeployments))
 }
 
-func deploymentRoutes(deployService services.DeploymentService, router gin.IRoutes) {
-	handleCreateDeployment(deployService, router)
-	handleListDeployments(deployService, router)
+func deploymentRoutes(deployService services.DeploymentService, listeners services.ListenerService, router gin.IRoutes) {
+	handleCreateDeployment(deployService, listeners, router)
+	handleListDeployments(deployService, listeners, router)
 }
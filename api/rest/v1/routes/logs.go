@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	v1 "github.com/ignis-runtime/ignis-wasmtime/api/rest/v1"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/handlers"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/middleware"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
+)
+
+// @Summary Get deployment logs
+// @Description Retrieves the guest stderr and host_log records captured from the deployment's most recent executions, newest first
+// @Tags Deployments
+// @Produce json
+// @Param uuid path string true "Deployment ID"
+// @Param limit query int false "Maximum number of entries to return (default 50)"
+// @Success 200 {object} v1.APIResponse{data=[]schemas.LogEntry}
+// @Failure 400 {object} v1.APIError
+// @Failure 404 {object} v1.APIError
+// @Failure 500 {object} v1.APIError
+// @Router /deploy/{uuid}/logs [get]
+func handleGetDeploymentLogs(runService services.RunService, deployService services.DeploymentService, router gin.IRoutes) {
+	logHandlers := handlers.NewLogHandlers(runService, deployService)
+	router.GET("/deploy/:uuid/logs", middleware.UUIDValidator(), v1.ErrorHandler(logHandlers.HandleGetDeploymentLogs))
+}
+
+func logRoutes(runService services.RunService, deployService services.DeploymentService, router gin.IRoutes) {
+	handleGetDeploymentLogs(runService, deployService, router)
+}
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
+)
+
+func metricsRoutes(router gin.IRoutes) {
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
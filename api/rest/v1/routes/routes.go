@@ -2,13 +2,18 @@ package routes
 
 import (
 	"github.com/ignis-runtime/ignis-wasmtime/api/rest/server"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/middleware"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/cache"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
 )
 
-func RegisterRoutes(server *server.Server, runService services.RunService, deployService services.DeploymentService, cache *cache.RedisCache) {
+func RegisterRoutes(server *server.Server, runService services.RunService, deployService services.DeploymentService, listeners services.ListenerService, cache *cache.RedisCache) {
+	server.Engine.Use(middleware.RequestID())
 	apiV1 := server.Engine.Group("/api/v1")
 
 	runRoutes(runService, deployService, apiV1)
-	deploymentRoutes(deployService, apiV1)
+	deploymentRoutes(deployService, listeners, apiV1)
+	logRoutes(runService, deployService, apiV1)
+	secretRoutes(deployService, apiV1)
+	metricsRoutes(server.Engine)
 }
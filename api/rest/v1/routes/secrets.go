@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	v1 "github.com/ignis-runtime/ignis-wasmtime/api/rest/v1"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/handlers"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/middleware"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
+)
+
+// @Summary List deployment secrets
+// @Description Lists the names of the secrets the deployment's guests read with host_secret_get; values are never returned
+// @Tags Secrets
+// @Produce json
+// @Param uuid path string true "Deployment ID"
+// @Success 200 {object} v1.APIResponse{data=[]string}
+// @Failure 400 {object} v1.APIError
+// @Failure 404 {object} v1.APIError
+// @Failure 500 {object} v1.APIError
+// @Router /deploy/{uuid}/secrets [get]
+func handleListSecrets(deployService services.DeploymentService, router gin.IRoutes) {
+	secretHandlers := handlers.NewSecretHandlers(deployService)
+	router.GET("/deploy/:uuid/secrets", middleware.UUIDValidator(), v1.ErrorHandler(secretHandlers.HandleListSecrets))
+}
+
+// @Summary Store a deployment secret
+// @Description Encrypts the value and attaches it to the deployment, replacing a secret of the same name. Guests read it with host_secret_get; it is never part of their environment
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Deployment ID"
+// @Param name path string true "Secret name: letters, digits, '_', '-' and '.'"
+// @Param secret body schemas.SecretRequest true "Secret value"
+// @Success 200 {object} v1.APIResponse
+// @Failure 400 {object} v1.APIError
+// @Failure 404 {object} v1.APIError
+// @Failure 500 {object} v1.APIError
+// @Router /deploy/{uuid}/secrets/{name} [put]
+func handleSetSecret(deployService services.DeploymentService, router gin.IRoutes) {
+	secretHandlers := handlers.NewSecretHandlers(deployService)
+	router.PUT("/deploy/:uuid/secrets/:name", middleware.UUIDValidator(), v1.ErrorHandler(secretHandlers.HandleSetSecret))
+}
+
+// @Summary Delete a deployment secret
+// @Description Removes a secret read with host_secret_get
+// @Tags Secrets
+// @Produce json
+// @Param uuid path string true "Deployment ID"
+// @Param name path string true "Secret name"
+// @Success 200 {object} v1.APIResponse
+// @Failure 400 {object} v1.APIError
+// @Failure 404 {object} v1.APIError
+// @Failure 500 {object} v1.APIError
+// @Router /deploy/{uuid}/secrets/{name} [delete]
+func handleDeleteSecret(deployService services.DeploymentService, router gin.IRoutes) {
+	secretHandlers := handlers.NewSecretHandlers(deployService)
+	router.DELETE("/deploy/:uuid/secrets/:name", middleware.UUIDValidator(), v1.ErrorHandler(secretHandlers.HandleDeleteSecret))
+}
+
+func secretRoutes(deployService services.DeploymentService, router gin.IRoutes) {
+	handleListSecrets(deployService, router)
+	handleSetSecret(deployService, router)
+	handleDeleteSecret(deployService, router)
+}
@@ -8,20 +8,59 @@ import (
 // DeployRequest represents the request body for creating a new deployment
 // @Description Deployment creation request
 type DeployRequest struct {
-	RuntimeType  string                `form:"runtime_type" binding:"required,oneof=js wasm"` // Runtime type (js or wasm)
-	File         *multipart.FileHeader `form:"file" binding:"required"`                       // Runtime file to deploy
-	PreopenedDir string                `form:"preopened_dir"`                                 // Preopened directory for WASI
-	Args         []string              `form:"args"`                                          // Arguments to pass to the runtime
+	RuntimeType      string                `form:"runtime_type" binding:"required,oneof=js wasm"` // Runtime type (js or wasm)
+	File             *multipart.FileHeader `form:"file" binding:"required"`                       // Runtime file to deploy
+	PreopenedDir     string                `form:"preopened_dir"`                                 // Directory under the sandbox root preopened for WASI (wasm only)
+	Args             []string              `form:"args"`                                          // Arguments passed to the module after its program name (wasm only)
+	TimeoutMs        int64                 `form:"timeout_ms" binding:"omitempty,min=0"`          // Per-execution wall-clock limit in milliseconds
+	FuelBudget       int64                 `form:"fuel_budget" binding:"omitempty,min=0"`         // Per-execution fuel budget
+	MaxMemoryBytes   int64                 `form:"max_memory_bytes" binding:"omitempty,min=0"`    // Maximum linear memory in bytes
+	MaxTableElements int64                 `form:"max_table_elements" binding:"omitempty,min=0"`  // Maximum elements per table
+	MaxInstances     int64                 `form:"max_instances" binding:"omitempty,min=0"`       // Maximum instances per store
+	MaxMemories      int64                 `form:"max_memories" binding:"omitempty,min=0"`        // Maximum linear memories per store
+	Env              []string              `form:"env"`                                           // Environment variables for the guest, as KEY=VALUE
+	Secrets          []string              `form:"secrets"`                                       // Secret environment variables, as KEY=VALUE; stored encrypted
+	WorkspaceMode    string                `form:"workspace_mode"`                                // Lifetime of the workspace mounted at /data: ephemeral (default), persistent or none
+	WorkspaceQuota   int64                 `form:"workspace_quota" binding:"omitempty,min=0"`     // Maximum size of the workspace in bytes
+	EgressHosts      []string              `form:"egress_hosts"`                                  // Hosts guests may connect to, e.g. api.example.com or *.example.com
+	EgressCIDRs      []string              `form:"egress_cidrs"`                                  // Networks guests may connect to; the only way to reach private addresses
+	EgressPorts      []int                 `form:"egress_ports"`                                  // Ports guests may connect to; empty allows every port
+	HTTPTimeoutMs    int64                 `form:"http_timeout_ms" binding:"omitempty,min=0"`     // Time limit of each outbound HTTP request in milliseconds
+	HTTPMaxBodyBytes int64                 `form:"http_max_body" binding:"omitempty,min=0"`       // Maximum outbound HTTP response body in bytes
+	HTTPMaxRedirects int64                 `form:"http_max_redirects" binding:"omitempty,min=0"`  // Redirects followed by outbound HTTP requests
+	Kind             string                `form:"kind" binding:"omitempty,oneof=http tcp"`       // How the deployment is invoked: http (default) or tcp
+	ListenPort       int                   `form:"listen_port" binding:"omitempty,max=65535"`     // Host port accepting connections (tcp only)
 }
 
 // DeployResponse represents the response body for a deployment
 // @Description Deployment response
 type DeployResponse struct {
-	ID          string    `json:"id"`           // Unique identifier for the deployment
-	IsExisting  bool      `json:"is_existing"`  // Indicates if this was an existing runtime with the same hash
-	RuntimeType string    `json:"runtime_type"` // Type of runtime (js or wasm)
-	Hash        string    `json:"hash"`         // Hash of the deployed file
-	S3FilePath  string    `json:"-"`            // Path to the file in S3 storage (not returned in API)
-	CreatedAt   time.Time `json:"created_at"`   // Creation timestamp
-	UpdatedAt   time.Time `json:"updated_at"`   // Last update timestamp
+	ID               string            `json:"id"`                 // Unique identifier for the deployment
+	IsExisting       bool              `json:"is_existing"`        // Indicates the file was already stored by a deployment with the same hash and is shared with it
+	RuntimeType      string            `json:"runtime_type"`       // Type of runtime (js or wasm)
+	Hash             string            `json:"hash"`               // Hash of the deployed file
+	S3FilePath       string            `json:"-"`                  // Path to the file in S3 storage (not returned in API)
+	TimeoutMs        int64             `json:"timeout_ms"`         // Per-execution wall-clock limit in milliseconds (0 uses the server default)
+	FuelBudget       int64             `json:"fuel_budget"`        // Per-execution fuel budget (0 uses the server default)
+	MaxMemoryBytes   int64             `json:"max_memory_bytes"`   // Maximum linear memory in bytes (0 uses the server default)
+	MaxTableElements int64             `json:"max_table_elements"` // Maximum elements per table (0 uses the server default)
+	MaxInstances     int64             `json:"max_instances"`      // Maximum instances per store (0 uses the server default)
+	MaxMemories      int64             `json:"max_memories"`       // Maximum linear memories per store (0 uses the server default)
+	PreopenedDir     string            `json:"preopened_dir"`      // Directory under the sandbox root preopened for WASI
+	Args             []string          `json:"args"`               // Arguments passed to the module after its program name
+	Env              map[string]string `json:"env"`                // Environment variables passed to the guest
+	SecretKeys       []string          `json:"secret_keys"`        // Names of the secret environment variables; values are never returned
+	HostSecretKeys   []string          `json:"host_secret_keys"`   // Names of the secrets read with host_secret_get; values are never returned
+	WorkspaceMode    string            `json:"workspace_mode"`     // Lifetime of the workspace mounted at /data
+	WorkspaceQuota   int64             `json:"workspace_quota"`    // Maximum size of the workspace in bytes (0 uses the server default)
+	EgressHosts      []string          `json:"egress_hosts"`       // Hosts guests may connect to
+	EgressCIDRs      []string          `json:"egress_cidrs"`       // Networks guests may connect to
+	EgressPorts      []int             `json:"egress_ports"`       // Ports guests may connect to; empty allows every port
+	HTTPTimeoutMs    int64             `json:"http_timeout_ms"`    // Time limit of each outbound HTTP request in milliseconds (0 uses the server default)
+	HTTPMaxBodyBytes int64             `json:"http_max_body"`      // Maximum outbound HTTP response body in bytes (0 uses the server default)
+	HTTPMaxRedirects int64             `json:"http_max_redirects"` // Redirects followed by outbound HTTP requests (0 uses the server default)
+	Kind             string            `json:"kind"`               // How the deployment is invoked: http or tcp
+	ListenPort       int               `json:"listen_port"`        // Host port accepting connections (tcp only)
+	CreatedAt        time.Time         `json:"created_at"`         // Creation timestamp
+	UpdatedAt        time.Time         `json:"updated_at"`         // Last update timestamp
 }
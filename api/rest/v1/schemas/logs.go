@@ -0,0 +1,24 @@
+package schemas
+
+import "time"
+
+// LogEntry represents the guest stderr and structured records captured from
+// one execution
+// @Description Guest log entry
+type LogEntry struct {
+	Time           time.Time   `json:"time"`                      // When the execution finished
+	RequestID      string      `json:"request_id"`                // ID of the request that triggered the execution
+	Stderr         string      `json:"stderr"`                    // Captured guest stderr
+	Truncated      bool        `json:"truncated"`                 // Whether stderr exceeded the size cap and was cut short
+	Records        []LogRecord `json:"records,omitempty"`         // Structured records emitted with host_log
+	RecordsDropped int         `json:"records_dropped,omitempty"` // Records left out by the rate limit or the retention limits
+}
+
+// LogRecord represents a structured record a guest emitted with host_log
+// @Description Guest log record
+type LogRecord struct {
+	Time    time.Time         `json:"time"`             // When the record was emitted
+	Level   string            `json:"level"`            // debug, info, warn or error
+	Message string            `json:"message"`          // Log message
+	Fields  map[string]string `json:"fields,omitempty"` // Key/value fields attached by the guest
+}
@@ -0,0 +1,7 @@
+package schemas
+
+// SecretRequest represents the request body for storing a host secret
+// @Description Host secret
+type SecretRequest struct {
+	Value string `json:"value" binding:"required"` // Plaintext value, encrypted before it is stored
+}
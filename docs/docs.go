@@ -100,14 +100,155 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Preopened directory for WASI",
+                        "description": "Directory relative to the server's sandbox root, preopened for WASI (wasm only)",
                         "name": "preopened_dir",
                         "in": "formData"
                     },
                     {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Arguments passed to the module after its program name (wasm only)",
+                        "name": "args",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Per-execution wall-clock limit in milliseconds (0 uses the server default)",
+                        "name": "timeout_ms",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Per-execution fuel budget (0 uses the server default)",
+                        "name": "fuel_budget",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum linear memory in bytes (0 uses the server default)",
+                        "name": "max_memory_bytes",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum elements per table (0 uses the server default)",
+                        "name": "max_table_elements",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum instances per store (0 uses the server default)",
+                        "name": "max_instances",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum linear memories per store (0 uses the server default)",
+                        "name": "max_memories",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Environment variables for the guest, as KEY=VALUE",
+                        "name": "env",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Secret environment variables, as KEY=VALUE; stored encrypted and never returned",
+                        "name": "secrets",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "none",
+                            "ephemeral",
+                            "persistent"
+                        ],
+                        "type": "string",
+                        "description": "Lifetime of the workspace mounted at /data, ephemeral by default",
+                        "name": "workspace_mode",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum size of the workspace in bytes (0 uses the server default)",
+                        "name": "workspace_quota",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Hosts guests may connect to, e.g. api.example.com or *.example.com",
+                        "name": "egress_hosts",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Networks guests may connect to; the only way to reach private addresses",
+                        "name": "egress_cidrs",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Ports guests may connect to; empty allows every port",
+                        "name": "egress_ports",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Time limit of each outbound HTTP request in milliseconds (0 uses the server default)",
+                        "name": "http_timeout_ms",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum outbound HTTP response body in bytes (0 uses the server default)",
+                        "name": "http_max_body",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Redirects followed by outbound HTTP requests (0 uses the server default)",
+                        "name": "http_max_redirects",
+                        "in": "formData"
+                    },
+                    {
+                        "enum": [
+                            "http",
+                            "tcp"
+                        ],
                         "type": "string",
-                        "description": "Arguments to pass to the runtime",
-                        "name": "args[]",
+                        "description": "How the deployment is invoked: through the run endpoint, or by connections to listen_port (wasm only)",
+                        "name": "kind",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Host port accepting connections, within the server's TCP port range (tcp only)",
+                        "name": "listen_port",
                         "in": "formData"
                     }
                 ],
@@ -144,6 +285,254 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/deploy/{uuid}/logs": {
+            "get": {
+                "description": "Retrieves the guest stderr and host_log records captured from the deployment's most recent executions, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Deployments"
+                ],
+                "summary": "Get deployment logs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries to return (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/schemas.LogEntry"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/deploy/{uuid}/secrets": {
+            "get": {
+                "description": "Lists the names of the secrets the deployment's guests read with host_secret_get; values are never returned",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Secrets"
+                ],
+                "summary": "List deployment secrets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/v1.APIResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    }
+                }
+            }
+        },
+        "/deploy/{uuid}/secrets/{name}": {
+            "put": {
+                "description": "Encrypts the value and attaches it to the deployment, replacing a secret of the same name. Guests read it with host_secret_get; it is never part of their environment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Secrets"
+                ],
+                "summary": "Store a deployment secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Secret name: letters, digits, '_', '-' and '.'",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Secret value",
+                        "name": "secret",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/schemas.SecretRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes a secret read with host_secret_get",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Secrets"
+                ],
+                "summary": "Delete a deployment secret",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Deployment ID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Secret name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/v1.APIError"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -151,29 +540,205 @@ const docTemplate = `{
             "description": "Deployment response",
             "type": "object",
             "properties": {
+                "args": {
+                    "description": "Arguments passed to the module after its program name",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "created_at": {
                     "description": "Creation timestamp",
                     "type": "string"
                 },
+                "egress_cidrs": {
+                    "description": "Networks guests may connect to",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "egress_hosts": {
+                    "description": "Hosts guests may connect to",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "egress_ports": {
+                    "description": "Ports guests may connect to; empty allows every port",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "env": {
+                    "description": "Environment variables passed to the guest",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "fuel_budget": {
+                    "description": "Per-execution fuel budget (0 uses the server default)",
+                    "type": "integer"
+                },
                 "hash": {
                     "description": "Hash of the deployed file",
                     "type": "string"
                 },
+                "host_secret_keys": {
+                    "description": "Names of the secrets read with host_secret_get; values are never returned",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "http_max_body": {
+                    "description": "Maximum outbound HTTP response body in bytes (0 uses the server default)",
+                    "type": "integer"
+                },
+                "http_max_redirects": {
+                    "description": "Redirects followed by outbound HTTP requests (0 uses the server default)",
+                    "type": "integer"
+                },
+                "http_timeout_ms": {
+                    "description": "Time limit of each outbound HTTP request in milliseconds (0 uses the server default)",
+                    "type": "integer"
+                },
                 "id": {
                     "description": "Unique identifier for the deployment",
                     "type": "string"
                 },
                 "is_existing": {
-                    "description": "Indicates if this was an existing runtime with the same hash",
+                    "description": "Indicates the file was already stored by a deployment with the same hash and is shared with it",
                     "type": "boolean"
                 },
+                "kind": {
+                    "description": "How the deployment is invoked: http or tcp",
+                    "type": "string"
+                },
+                "listen_port": {
+                    "description": "Host port accepting connections (tcp only)",
+                    "type": "integer"
+                },
+                "max_instances": {
+                    "description": "Maximum instances per store (0 uses the server default)",
+                    "type": "integer"
+                },
+                "max_memories": {
+                    "description": "Maximum linear memories per store (0 uses the server default)",
+                    "type": "integer"
+                },
+                "max_memory_bytes": {
+                    "description": "Maximum linear memory in bytes (0 uses the server default)",
+                    "type": "integer"
+                },
+                "max_table_elements": {
+                    "description": "Maximum elements per table (0 uses the server default)",
+                    "type": "integer"
+                },
+                "preopened_dir": {
+                    "description": "Directory under the sandbox root preopened for WASI",
+                    "type": "string"
+                },
                 "runtime_type": {
                     "description": "Type of runtime (js or wasm)",
                     "type": "string"
                 },
+                "secret_keys": {
+                    "description": "Names of the secret environment variables; values are never returned",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "timeout_ms": {
+                    "description": "Per-execution wall-clock limit in milliseconds (0 uses the server default)",
+                    "type": "integer"
+                },
                 "updated_at": {
                     "description": "Last update timestamp",
                     "type": "string"
+                },
+                "workspace_mode": {
+                    "description": "Lifetime of the workspace mounted at /data",
+                    "type": "string"
+                },
+                "workspace_quota": {
+                    "description": "Maximum size of the workspace in bytes (0 uses the server default)",
+                    "type": "integer"
+                }
+            }
+        },
+        "schemas.LogEntry": {
+            "description": "Guest log entry",
+            "type": "object",
+            "properties": {
+                "records": {
+                    "description": "Structured records emitted with host_log",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/schemas.LogRecord"
+                    }
+                },
+                "records_dropped": {
+                    "description": "Records left out by the rate limit or the retention limits",
+                    "type": "integer"
+                },
+                "request_id": {
+                    "description": "ID of the request that triggered the execution",
+                    "type": "string"
+                },
+                "stderr": {
+                    "description": "Captured guest stderr",
+                    "type": "string"
+                },
+                "time": {
+                    "description": "When the execution finished",
+                    "type": "string"
+                },
+                "truncated": {
+                    "description": "Whether stderr exceeded the size cap and was cut short",
+                    "type": "boolean"
+                }
+            }
+        },
+        "schemas.LogRecord": {
+            "description": "Guest log record",
+            "type": "object",
+            "properties": {
+                "fields": {
+                    "description": "Key/value fields attached by the guest",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "level": {
+                    "description": "debug, info, warn or error",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "Log message",
+                    "type": "string"
+                },
+                "time": {
+                    "description": "When the record was emitted",
+                    "type": "string"
+                }
+            }
+        },
+        "schemas.SecretRequest": {
+            "description": "Host secret",
+            "type": "object",
+            "required": [
+                "value"
+            ],
+            "properties": {
+                "value": {
+                    "description": "Plaintext value, encrypted before it is stored",
+                    "type": "string"
                 }
             }
         },
@@ -0,0 +1,149 @@
+//go:build wasip1
+
+/*
+Package hostcall calls the Ignis host functions with the two-phase ABI, so
+responses of any size reach the guest.
+
+Each call passes a buffer for the response. When the response doesn't fit,
+the host keeps it under a handle and reports its length, and the guest
+fetches it with host_response_read into a buffer of that size. Failures of
+the call itself are distinct negative status codes, returned as *Error.
+
+Requests and responses are the protobuf messages of the types package,
+exchanged through the _v2 imports.
+
+It stands in for the v1 go-sdk packages, which use the legacy imports and
+fail on any response larger than their fixed buffers.
+*/
+package hostcall
+
+import (
+	"fmt"
+	"unsafe"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// Status codes of the host calls, mirroring the host's ErrCode constants.
+const (
+	CodeMemory         int32 = -1
+	CodeNoState        int32 = -2
+	CodeInvalidRequest int32 = -3
+	CodeInternal       int32 = -4
+	CodeBadHandle      int32 = -5
+	CodeBufferTooSmall int32 = -6
+	CodeTooManyPending int32 = -7
+	CodeIO             int32 = -8
+	CodeBadState       int32 = -9
+	CodeTooManyStreams int32 = -10
+	CodeNotFound       int32 = -11
+	CodeQuotaExceeded  int32 = -12
+	CodeUnavailable    int32 = -13
+	CodeNotInteger     int32 = -14
+)
+
+// initialBufferSize is the response buffer passed on the first attempt, large
+// enough for most responses to arrive in a single call.
+const initialBufferSize = 4096
+
+var codeNames = map[int32]string{
+	CodeMemory:         "invalid guest memory",
+	CodeNoState:        "no host state",
+	CodeInvalidRequest: "invalid request",
+	CodeInternal:       "internal host error",
+	CodeBadHandle:      "unknown response handle",
+	CodeBufferTooSmall: "buffer too small",
+	CodeTooManyPending: "too many pending responses",
+	CodeIO:             "stream failed",
+	CodeBadState:       "call out of order",
+	CodeTooManyStreams: "too many open streams",
+	CodeNotFound:       "not found",
+	CodeQuotaExceeded:  "quota exceeded",
+	CodeUnavailable:    "unavailable",
+	CodeNotInteger:     "not an integer",
+}
+
+// Error is a host call that failed with a status code.
+type Error struct {
+	Func string
+	Code int32
+}
+
+func (e *Error) Error() string {
+	name, ok := codeNames[e.Code]
+	if !ok {
+		name = "unknown error"
+	}
+	return fmt.Sprintf("%s: %s (%d)", e.Func, name, e.Code)
+}
+
+//go:wasmimport env host_http_call_v2
+func hostHTTPCall(reqPtr, reqLen, respPtr, respLen, handlePtr uint32) int32
+
+//go:wasmimport env host_socket_call_v2
+func hostSocketCall(reqPtr, reqLen, respPtr, respLen, handlePtr uint32) int32
+
+//go:wasmimport env host_response_read
+func hostResponseRead(handle, bufPtr, bufLen uint32) int32
+
+//go:wasmimport env host_response_drop
+func hostResponseDrop(handle uint32) int32
+
+// HTTP performs req through host_http_call_v2.
+func HTTP(req *types.HostHTTPRequest) (*types.HostHTTPResponse, error) {
+	var resp types.HostHTTPResponse
+	if err := call("host_http_call_v2", hostHTTPCall, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Socket performs req through host_socket_call_v2.
+func Socket(req *types.HostSocketRequest) (*types.HostSocketResponse, error) {
+	var resp types.HostSocketResponse
+	if err := call("host_socket_call_v2", hostSocketCall, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func call(name string, fn func(reqPtr, reqLen, respPtr, respLen, handlePtr uint32) int32, req, resp proto.Message) error {
+	reqBytes, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	respBytes, err := exchange(name, fn, reqBytes)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(respBytes, resp)
+}
+
+func exchange(name string, fn func(reqPtr, reqLen, respPtr, respLen, handlePtr uint32) int32, req []byte) ([]byte, error) {
+	resp := make([]byte, initialBufferSize)
+	var handle uint32
+	n := fn(bufferPtr(req), uint32(len(req)), bufferPtr(resp), uint32(len(resp)), uint32(uintptr(unsafe.Pointer(&handle))))
+	if n < 0 {
+		return nil, &Error{Func: name, Code: n}
+	}
+	if handle == 0 {
+		return resp[:n], nil
+	}
+
+	// The response didn't fit; fetch it with a buffer of its size.
+	resp = make([]byte, n)
+	if n := hostResponseRead(handle, bufferPtr(resp), uint32(len(resp))); n < 0 {
+		hostResponseDrop(handle)
+		return nil, &Error{Func: "host_response_read", Code: n}
+	}
+	return resp, nil
+}
+
+func bufferPtr(b []byte) uint32 {
+	if len(b) == 0 {
+		return 0
+	}
+	return uint32(uintptr(unsafe.Pointer(&b[0])))
+}
@@ -0,0 +1,80 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// Transport is an http.RoundTripper making requests through the host, for
+// use as the Transport of an http.Client. Bodies are streamed, so they needn't
+// fit in guest memory. The host follows redirects itself.
+type Transport struct{}
+
+// RoundTrip implements http.RoundTripper.
+func (Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hostReq := &types.HostHTTPRequest{
+		Method:  req.Method,
+		Url:     req.URL.String(),
+		Headers: make(map[string]*types.HeaderFields, len(req.Header)+1),
+	}
+	for name, values := range req.Header {
+		hostReq.Headers[name] = &types.HeaderFields{Fields: values}
+	}
+	if req.ContentLength > 0 {
+		hostReq.Headers["Content-Length"] = &types.HeaderFields{Fields: []string{strconv.FormatInt(req.ContentLength, 10)}}
+	}
+
+	stream, err := OpenHTTP(hostReq)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	if req.Body != nil {
+		// A failed write is explained by the response below.
+		io.Copy(stream, req.Body)
+		req.Body.Close()
+	}
+
+	hostResp, err := stream.Response()
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	if hostResp.Error != "" {
+		stream.Close()
+		return nil, &OpError{Op: req.Method, Code: hostResp.Code, Err: hostResp.Error}
+	}
+	if hostResp.StatusCode == 0 {
+		stream.Close()
+		return nil, errors.New("host returned no status")
+	}
+
+	header := make(http.Header, len(hostResp.Headers))
+	for name, values := range hostResp.Headers {
+		header[name] = values.GetFields()
+	}
+	contentLength := int64(-1)
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		contentLength = length
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", hostResp.StatusCode, http.StatusText(int(hostResp.StatusCode))),
+		StatusCode:    int(hostResp.StatusCode),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          stream,
+		ContentLength: contentLength,
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,40 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+//go:wasmimport env host_invoke
+func hostInvoke(reqPtr, reqLen, respPtr, respLen, handlePtr uint32) int32
+
+// Invoke runs the deployment target with req inside the host, as if req had
+// been sent to its run endpoint, and returns its response. target must be a
+// deployment UUID; deployments have no aliases, so any other target fails
+// with "not_found". The call shares the caller's deadline. The host tells the
+// target who called in the X-Ignis-Caller header, and refuses calls nesting
+// too deep or reaching a deployment already in the call chain; such failures
+// are returned as *OpError, with a code such as "not_found",
+// "depth_exceeded", "cycle", "timeout" or "invoke_failed". The host keeps
+// the details of a failure in its own log.
+func Invoke(target string, req *types.FDRequest) (*types.FDResponse, error) {
+	reqBytes, err := proto.Marshal(&types.HostInvokeRequest{Target: target, Request: req})
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := exchange("host_invoke", hostInvoke, reqBytes)
+	if err != nil {
+		return nil, err
+	}
+	var resp types.HostInvokeResponse
+	if err := proto.Unmarshal(respBytes, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &OpError{Op: "invoke " + target, Code: resp.Code, Err: resp.Error}
+	}
+	return resp.Response, nil
+}
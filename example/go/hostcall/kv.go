@@ -0,0 +1,91 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"errors"
+	"time"
+	"unsafe"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// ErrNotFound is returned by Get for a key that doesn't exist or has
+// expired, and by Secret for a secret the deployment doesn't have.
+var ErrNotFound = errors.New("hostcall: not found")
+
+//go:wasmimport env host_kv_get
+func hostKVGet(keyPtr, keyLen, respPtr, respLen, handlePtr uint32) int32
+
+//go:wasmimport env host_kv_set
+func hostKVSet(keyPtr, keyLen, valPtr, valLen uint32, ttlMs int64) int32
+
+//go:wasmimport env host_kv_delete
+func hostKVDelete(keyPtr, keyLen uint32) int32
+
+//go:wasmimport env host_kv_incr
+func hostKVIncr(keyPtr, keyLen uint32, delta int64, resultPtr uint32) int32
+
+//go:wasmimport env host_kv_list
+func hostKVList(prefixPtr, prefixLen, respPtr, respLen, handlePtr uint32) int32
+
+// The key-value functions work on the deployment's own namespace, kept by
+// the host across invocations. Keys are non-empty UTF-8 strings of up to 512
+// bytes; the host bounds the number of keys and the size of each value, and
+// writes past those fail with CodeQuotaExceeded.
+
+// Get returns the value stored under key, or ErrNotFound.
+func Get(key string) ([]byte, error) {
+	value, err := exchange("host_kv_get", hostKVGet, []byte(key))
+	if err, ok := err.(*Error); ok && err.Code == CodeNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Set stores value under key. A positive ttl makes the key expire after it;
+// zero keeps it until deleted.
+func Set(key string, value []byte, ttl time.Duration) error {
+	k := []byte(key)
+	if n := hostKVSet(bufferPtr(k), uint32(len(k)), bufferPtr(value), uint32(len(value)), ttl.Milliseconds()); n < 0 {
+		return &Error{Func: "host_kv_set", Code: n}
+	}
+	return nil
+}
+
+// Delete removes key, reporting whether it existed.
+func Delete(key string) (bool, error) {
+	k := []byte(key)
+	n := hostKVDelete(bufferPtr(k), uint32(len(k)))
+	if n < 0 {
+		return false, &Error{Func: "host_kv_delete", Code: n}
+	}
+	return n == 1, nil
+}
+
+// Incr atomically adds delta to the integer stored under key, which starts
+// at 0, and returns the result.
+func Incr(key string, delta int64) (int64, error) {
+	k := []byte(key)
+	var result int64
+	if n := hostKVIncr(bufferPtr(k), uint32(len(k)), delta, uint32(uintptr(unsafe.Pointer(&result)))); n < 0 {
+		return 0, &Error{Func: "host_kv_incr", Code: n}
+	}
+	return result, nil
+}
+
+// List returns the keys starting with prefix, sorted. An empty prefix lists
+// every key.
+func List(prefix string) ([]string, error) {
+	respBytes, err := exchange("host_kv_list", hostKVList, []byte(prefix))
+	if err != nil {
+		return nil, err
+	}
+	var keys types.HostKVKeys
+	if err := proto.Unmarshal(respBytes, &keys); err != nil {
+		return nil, err
+	}
+	return keys.Keys, nil
+}
@@ -0,0 +1,102 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"context"
+	"log/slog"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+//go:wasmimport env host_log
+func hostLog(recPtr, recLen uint32) int32
+
+// LogHandler is a slog.Handler emitting records through host_log, so they
+// reach the server log and the deployment's logs tagged with the deployment
+// and request IDs:
+//
+//	logger := slog.New(hostcall.LogHandler{})
+//	logger.Info("cache miss", "key", key)
+//
+// Attributes become the record's fields, with groups flattened into dotted
+// keys. Records over the host's rate limit are dropped.
+type LogHandler struct {
+	attrs  []*types.HostLogField
+	prefix string
+}
+
+// Enabled implements slog.Handler; the host keeps every level.
+func (h LogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h LogHandler) Handle(_ context.Context, r slog.Record) error {
+	record := &types.HostLogRecord{
+		Level:   logLevelName(r.Level),
+		Message: r.Message,
+		Fields:  append([]*types.HostLogField(nil), h.attrs...),
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		record.Fields = appendAttr(record.Fields, h.prefix, attr)
+		return true
+	})
+	recBytes, err := proto.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if n := hostLog(bufferPtr(recBytes), uint32(len(recBytes))); n < 0 {
+		return &Error{Func: "host_log", Code: n}
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := append([]*types.HostLogField(nil), h.attrs...)
+	for _, attr := range attrs {
+		fields = appendAttr(fields, h.prefix, attr)
+	}
+	return LogHandler{attrs: fields, prefix: h.prefix}
+}
+
+// WithGroup implements slog.Handler.
+func (h LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return LogHandler{attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+func appendAttr(fields []*types.HostLogField, prefix string, attr slog.Attr) []*types.HostLogField {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			fields = appendAttr(fields, prefix, member)
+		}
+		return fields
+	}
+	return append(fields, &types.HostLogField{Key: prefix + attr.Key, Value: attr.Value.String()})
+}
+
+func logLevelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	default:
+		return "error"
+	}
+}
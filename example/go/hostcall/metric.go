@@ -0,0 +1,61 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+//go:wasmimport env host_metric
+func hostMetric(samplePtr, sampleLen uint32) int32
+
+// The metric functions record samples the host aggregates per deployment and
+// exposes as ignis_guest_<kind>_<name>, with a deployment_id label. Labels
+// are given as key/value pairs:
+//
+//	hostcall.Count("cache_lookups_total", 1, "result", "hit")
+//
+// Each distinct set of labels is a series, and the host bounds the series a
+// deployment may create; samples starting a series past that fail with
+// CodeQuotaExceeded.
+
+// Count adds v, which can't be negative, to a counter.
+func Count(name string, v float64, labels ...string) error {
+	return recordMetric(&types.HostMetric{Kind: "counter", Name: name, Value: v}, labels)
+}
+
+// SetGauge sets a gauge to v.
+func SetGauge(name string, v float64, labels ...string) error {
+	return recordMetric(&types.HostMetric{Kind: "gauge", Name: name, Value: v}, labels)
+}
+
+// AddGauge adds delta, which may be negative, to a gauge.
+func AddGauge(name string, delta float64, labels ...string) error {
+	return recordMetric(&types.HostMetric{Kind: "gauge", Name: name, Value: delta, Delta: true}, labels)
+}
+
+// Observe records v in a histogram whose buckets suit durations in seconds.
+func Observe(name string, v float64, labels ...string) error {
+	return recordMetric(&types.HostMetric{Kind: "histogram", Name: name, Value: v}, labels)
+}
+
+func recordMetric(sample *types.HostMetric, labels []string) error {
+	if len(labels)%2 != 0 {
+		return errors.New("hostcall: metric labels must be key/value pairs")
+	}
+	for i := 0; i < len(labels); i += 2 {
+		sample.Labels = append(sample.Labels, &types.HostMetricLabel{Key: labels[i], Value: labels[i+1]})
+	}
+	sampleBytes, err := proto.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	if n := hostMetric(bufferPtr(sampleBytes), uint32(len(sampleBytes))); n < 0 {
+		return &Error{Func: "host_metric", Code: n}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// OpError is an operation the host carried out but that failed, such as a
+// dial refused by the deployment's egress policy.
+type OpError struct {
+	Op   string
+	Code string // e.g. "egress_denied"; empty if the host gave none
+	Err  string
+}
+
+func (e *OpError) Error() string {
+	if e.Code != "" {
+		return e.Op + ": " + e.Err + " (" + e.Code + ")"
+	}
+	return e.Op + ": " + e.Err
+}
+
+// Conn is a TCP or UDP connection held by the host.
+type Conn struct {
+	fd          int32
+	readTimeout time.Duration
+}
+
+// Dial connects to address, a host:port, over TCP.
+func Dial(address string) (*Conn, error) {
+	return DialNetwork("tcp", address)
+}
+
+// DialNetwork connects to address, a host:port, over network: tcp or udp,
+// optionally suffixed with 4 or 6.
+func DialNetwork(network, address string) (*Conn, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "dial", Address: address, Network: network})
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{fd: resp.Fd}, nil
+}
+
+// Inbound returns the connection a TCP deployment was started to serve. The
+// host passes its descriptor in IGNIS_SOCKET_FD.
+func Inbound() (*Conn, error) {
+	fd, err := strconv.Atoi(os.Getenv("IGNIS_SOCKET_FD"))
+	if err != nil {
+		return nil, errors.New("hostcall: no inbound connection; is this a tcp deployment?")
+	}
+	return &Conn{fd: int32(fd)}, nil
+}
+
+// SetReadTimeout bounds how long each Read waits for data. Zero waits until
+// the execution's deadline.
+func (c *Conn) SetReadTimeout(d time.Duration) {
+	c.readTimeout = d
+}
+
+// Read reads up to len(b) bytes from the connection.
+func (c *Conn) Read(b []byte) (int, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "read", Fd: c.fd, Size: int32(len(b)), TimeoutMs: int32(c.readTimeout.Milliseconds())})
+	if opErr, ok := err.(*OpError); ok && opErr.Err == io.EOF.Error() {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	return copy(b, resp.Data), nil
+}
+
+// Write writes b to the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "write", Fd: c.fd, Data: b})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.BytesSent), nil
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	_, err := socket(&types.HostSocketRequest{Operation: "close", Fd: c.fd})
+	return err
+}
+
+// PacketConn is an unconnected UDP socket held by the host.
+type PacketConn struct {
+	fd          int32
+	readTimeout time.Duration
+}
+
+// ListenPacket opens an unconnected UDP socket on network: udp, udp4 or
+// udp6. The host binds it on the first WriteTo, and ReadFrom only returns
+// datagrams from addresses written to.
+func ListenPacket(network string) (*PacketConn, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "dial", Network: network})
+	if err != nil {
+		return nil, err
+	}
+	return &PacketConn{fd: resp.Fd}, nil
+}
+
+// SetReadTimeout bounds how long each ReadFrom waits for a datagram. Zero
+// waits until the execution's deadline.
+func (c *PacketConn) SetReadTimeout(d time.Duration) {
+	c.readTimeout = d
+}
+
+// ReadFrom reads a datagram into b and returns its length and sender.
+func (c *PacketConn) ReadFrom(b []byte) (int, string, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "recvfrom", Fd: c.fd, Size: int32(len(b)), TimeoutMs: int32(c.readTimeout.Milliseconds())})
+	if err != nil {
+		return 0, "", err
+	}
+	return copy(b, resp.Data), resp.Address, nil
+}
+
+// WriteTo sends b as a datagram to address, a host:port.
+func (c *PacketConn) WriteTo(b []byte, address string) (int, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "sendto", Fd: c.fd, Address: address, Data: b})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.BytesSent), nil
+}
+
+// Close closes the socket.
+func (c *PacketConn) Close() error {
+	_, err := socket(&types.HostSocketRequest{Operation: "close", Fd: c.fd})
+	return err
+}
+
+// LookupHost returns the addresses of host on network: ip, ip4 or ip6.
+func LookupHost(network, host string) ([]string, error) {
+	resp, err := socket(&types.HostSocketRequest{Operation: "resolve", Address: host, Network: network})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Addresses, nil
+}
+
+func socket(req *types.HostSocketRequest) (*types.HostSocketResponse, error) {
+	resp, err := Socket(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, &OpError{Op: req.Operation, Code: resp.Code, Err: resp.Error}
+	}
+	return resp, nil
+}
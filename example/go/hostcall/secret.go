@@ -0,0 +1,17 @@
+//go:build wasip1
+
+package hostcall
+
+//go:wasmimport env host_secret_get
+func hostSecretGet(namePtr, nameLen, respPtr, respLen, handlePtr uint32) int32
+
+// Secret returns the value of the deployment's secret name, or ErrNotFound.
+// Secrets are attached through the deployment's secrets endpoints and never
+// appear in the environment, so keep the value out of logs and responses.
+func Secret(name string) ([]byte, error) {
+	value, err := exchange("host_secret_get", hostSecretGet, []byte(name))
+	if err, ok := err.(*Error); ok && err.Code == CodeNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
@@ -0,0 +1,97 @@
+//go:build wasip1
+
+package hostcall
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+//go:wasmimport env host_http_open
+func hostHTTPOpen(reqPtr, reqLen uint32) int32
+
+//go:wasmimport env host_http_write
+func hostHTTPWrite(handle, bufPtr, bufLen uint32) int32
+
+//go:wasmimport env host_http_response
+func hostHTTPResponse(handle, respPtr, respLen, handlePtr uint32) int32
+
+//go:wasmimport env host_http_read
+func hostHTTPRead(handle, bufPtr, bufLen uint32) int32
+
+//go:wasmimport env host_http_close
+func hostHTTPClose(handle uint32) int32
+
+// Stream is an HTTP request whose bodies pass through the guest in chunks,
+// so neither has to fit in guest memory at once. Write the request body,
+// call Response, then Read the response body until io.EOF.
+type Stream struct {
+	handle uint32
+}
+
+// OpenHTTP starts req. Its Body, if any, is sent before anything written to
+// the stream. Set a Content-Length header to send the body with a fixed
+// length rather than chunked.
+func OpenHTTP(req *types.HostHTTPRequest) (*Stream, error) {
+	reqBytes, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	handle := hostHTTPOpen(bufferPtr(reqBytes), uint32(len(reqBytes)))
+	if handle < 0 {
+		return nil, &Error{Func: "host_http_open", Code: handle}
+	}
+	return &Stream{handle: uint32(handle)}, nil
+}
+
+// Write sends b as the next chunk of the request body. If it fails, Response
+// reports why.
+func (s *Stream) Write(b []byte) (int, error) {
+	n := hostHTTPWrite(s.handle, bufferPtr(b), uint32(len(b)))
+	if n < 0 {
+		return 0, &Error{Func: "host_http_write", Code: n}
+	}
+	return int(n), nil
+}
+
+// Response ends the request body and waits for the response. The response
+// has no Body; read it from the stream instead.
+func (s *Stream) Response() (*types.HostHTTPResponse, error) {
+	respBytes, err := exchange("host_http_response", func(_, _, respPtr, respLen, handlePtr uint32) int32 {
+		return hostHTTPResponse(s.handle, respPtr, respLen, handlePtr)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp types.HostHTTPResponse
+	if err := proto.Unmarshal(respBytes, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Read reads the next chunk of the response body.
+func (s *Stream) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n := hostHTTPRead(s.handle, bufferPtr(b), uint32(len(b)))
+	if n < 0 {
+		return 0, &Error{Func: "host_http_read", Code: n}
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return int(n), nil
+}
+
+// Close releases the stream.
+func (s *Stream) Close() error {
+	if code := hostHTTPClose(s.handle); code < 0 {
+		return &Error{Func: "host_http_close", Code: code}
+	}
+	return nil
+}
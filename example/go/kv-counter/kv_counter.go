@@ -0,0 +1,123 @@
+//go:build wasip1
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ignis-runtime/go-sdk/sdk"
+
+	"github.com/ignis-runtime/ignis-wasmtime/example/go/hostcall"
+)
+
+// A visit counter and note store kept in the deployment's key-value
+// namespace, so it survives across invocations. Visits and request durations
+// are also recorded as the deployment's metrics:
+//
+//	GET    /              counts the visit
+//	GET    /notes         lists the notes
+//	GET    /notes/{name}  returns a note
+//	PUT    /notes/{name}  stores the body, expiring after ?ttl= if given
+//	DELETE /notes/{name}  removes a note
+const notePrefix = "note:"
+
+// logger sends structured records to the deployment's logs through host_log.
+var logger = slog.New(hostcall.LogHandler{})
+
+func main() {
+	r := chi.NewRouter()
+	r.Use(observeDuration)
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		visits, err := hostcall.Incr("visits", 1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Info("visit", "count", visits)
+		hostcall.Count("kv_counter_visits_total", 1)
+		fmt.Fprintf(w, "Visit number %d\n", visits)
+	})
+
+	r.Get("/notes", func(w http.ResponseWriter, r *http.Request) {
+		keys, err := hostcall.List(notePrefix)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, key := range keys {
+			fmt.Fprintln(w, strings.TrimPrefix(key, notePrefix))
+		}
+	})
+
+	r.Get("/notes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		note, err := hostcall.Get(notePrefix + chi.URLParam(r, "name"))
+		if errors.Is(err, hostcall.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(note)
+	})
+
+	r.Put("/notes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var ttl time.Duration
+		if s := r.URL.Query().Get("ttl"); s != "" {
+			var err error
+			if ttl, err = time.ParseDuration(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		note, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = hostcall.Set(notePrefix+chi.URLParam(r, "name"), note, ttl)
+		var hostErr *hostcall.Error
+		if errors.As(err, &hostErr) && hostErr.Code == hostcall.CodeQuotaExceeded {
+			logger.Warn("note rejected", "name", chi.URLParam(r, "name"), "size", len(note))
+			http.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	r.Delete("/notes/{name}", func(w http.ResponseWriter, r *http.Request) {
+		existed, err := hostcall.Delete(notePrefix + chi.URLParam(r, "name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !existed {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	sdk.Handle(r, nil)
+}
+
+// observeDuration records how long each request took, by method, in the
+// deployment's metrics.
+func observeDuration(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		hostcall.Observe("kv_counter_request_duration_seconds", time.Since(start).Seconds(), "method", r.Method)
+	})
+}
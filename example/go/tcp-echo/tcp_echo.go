@@ -0,0 +1,38 @@
+//go:build wasip1
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ignis-runtime/ignis-wasmtime/example/go/hostcall"
+)
+
+// A line-based echo server, deployed with kind=tcp. Each connection starts a
+// fresh instance, which echoes every line back until the client sends "quit"
+// or hangs up.
+func main() {
+	conn, err := hostcall.Inbound()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(os.Stderr, "Serving %s\n", os.Getenv("IGNIS_REMOTE_ADDR"))
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "quit" {
+			break
+		}
+		if _, err := fmt.Fprintf(conn, "echo: %s\n", line); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write: %v\n", err)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Connection from %s closed\n", os.Getenv("IGNIS_REMOTE_ADDR"))
+}
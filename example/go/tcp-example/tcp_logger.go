@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/ignis-runtime/go-sdk/sdk/net"
+	"github.com/ignis-runtime/ignis-wasmtime/example/go/hostcall"
 )
 
 func main() {
-	// Connect to our local TCP server using the net package SDK
-	conn, err := net.Dial("tcp", "127.0.0.1:30072")
+	// Connect to our local TCP server through the host. hostcall uses the
+	// two-phase host call ABI, so reads aren't limited by a response buffer.
+	conn, err := hostcall.Dial("127.0.0.1:30072")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect to 127.0.0.1:30072: %v\n", err)
 		return
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
+	"github.com/redis/go-redis/v9"
+)
+
+// Every namespace keeps an index of its live keys, a sorted set scored by
+// expiry time in milliseconds (+inf for keys without a TTL). The scripts
+// below drop expired entries before counting, so the key quota is checked
+// atomically against the keys that still exist.
+
+// setScript stores ARGV[3] under KEYS[2] unless the namespace holds ARGV[5]
+// other keys already. KEYS[1] is the index, ARGV[1] the current time,
+// ARGV[2] the index member and ARGV[4] the TTL in milliseconds, or 0.
+var setScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) and redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[5]) then
+  return 0
+end
+local ttl = tonumber(ARGV[4])
+if ttl > 0 then
+  redis.call('SET', KEYS[2], ARGV[3], 'PX', ttl)
+  redis.call('ZADD', KEYS[1], tonumber(ARGV[1]) + ttl, ARGV[2])
+else
+  redis.call('SET', KEYS[2], ARGV[3])
+  redis.call('ZADD', KEYS[1], '+inf', ARGV[2])
+end
+return 1
+`)
+
+// incrScript adds ARGV[3] to the integer under KEYS[2], creating it unless
+// the namespace holds ARGV[4] keys already. It returns {0, 0} when over
+// quota and {1, value} otherwise. An existing key keeps its TTL.
+var incrScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+  if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[4]) then
+    return {0, 0}
+  end
+  redis.call('ZADD', KEYS[1], '+inf', ARGV[2])
+end
+return {1, redis.call('INCRBY', KEYS[2], ARGV[3])}
+`)
+
+// RedisKV is a kv.Store in Redis. Each namespace may hold
+// maxKeys keys of up to maxValueBytes each.
+type RedisKV struct {
+	client        *redis.Client
+	maxKeys       int
+	maxValueBytes int
+}
+
+// KV returns a key-value store sharing the cache's Redis connection.
+func (c *RedisCache) KV(maxKeys, maxValueBytes int) *RedisKV {
+	return &RedisKV{client: c.client, maxKeys: maxKeys, maxValueBytes: maxValueBytes}
+}
+
+// indexKey and dataKey share a hash tag, so a namespace stays within one
+// Redis Cluster slot and the scripts may touch both.
+func indexKey(namespace string) string {
+	return "ignis:kv:{" + namespace + "}:index"
+}
+
+func dataKey(namespace, key string) string {
+	return "ignis:kv:{" + namespace + "}:k:" + key
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixMilli()
+}
+
+// unavailable wraps a Redis failure for the host functions.
+func unavailable(err error) error {
+	return fmt.Errorf("%w: %v", kv.ErrUnavailable, err)
+}
+
+func (s *RedisKV) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	value, err := s.client.Get(ctx, dataKey(namespace, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, kv.ErrNotFound
+	} else if err != nil {
+		return nil, unavailable(err)
+	}
+	return value, nil
+}
+
+func (s *RedisKV) Set(ctx context.Context, namespace, key string, value []byte, ttl time.Duration) error {
+	if len(value) > s.maxValueBytes {
+		return fmt.Errorf("%w: value larger than %d bytes", kv.ErrQuotaExceeded, s.maxValueBytes)
+	}
+	stored, err := setScript.Run(ctx, s.client,
+		[]string{indexKey(namespace), dataKey(namespace, key)},
+		nowMillis(), key, value, ttl.Milliseconds(), s.maxKeys,
+	).Int()
+	if err != nil {
+		return unavailable(err)
+	}
+	if stored == 0 {
+		return fmt.Errorf("%w: more than %d keys", kv.ErrQuotaExceeded, s.maxKeys)
+	}
+	return nil
+}
+
+func (s *RedisKV) Delete(ctx context.Context, namespace, key string) (bool, error) {
+	var deleted *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, dataKey(namespace, key))
+		pipe.ZRem(ctx, indexKey(namespace), key)
+		return nil
+	})
+	if err != nil {
+		return false, unavailable(err)
+	}
+	return deleted.Val() > 0, nil
+}
+
+func (s *RedisKV) Incr(ctx context.Context, namespace, key string, delta int64) (int64, error) {
+	result, err := incrScript.Run(ctx, s.client,
+		[]string{indexKey(namespace), dataKey(namespace, key)},
+		nowMillis(), key, delta, s.maxKeys,
+	).Int64Slice()
+	if err != nil {
+		if strings.Contains(err.Error(), "not an integer") {
+			return 0, kv.ErrNotInteger
+		}
+		return 0, unavailable(err)
+	}
+	if len(result) != 2 {
+		return 0, unavailable(fmt.Errorf("unexpected script result %v", result))
+	}
+	if result[0] == 0 {
+		return 0, fmt.Errorf("%w: more than %d keys", kv.ErrQuotaExceeded, s.maxKeys)
+	}
+	return result[1], nil
+}
+
+// List reads the namespace's index, which holds at most maxKeys live keys,
+// and filters it by prefix.
+func (s *RedisKV) List(ctx context.Context, namespace, prefix string) ([]string, error) {
+	members, err := s.client.ZRangeByScore(ctx, indexKey(namespace), &redis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", nowMillis()),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	keys := make([]string, 0, len(members))
+	for _, member := range members {
+		if strings.HasPrefix(member, prefix) {
+			keys = append(keys, member)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
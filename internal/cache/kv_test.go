@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
+)
+
+// testKV returns a store on the Redis server at REDIS_TEST_ADDR, skipping
+// the test without one, and a namespace of its own.
+func testKV(t *testing.T, maxKeys, maxValueBytes int) (*RedisKV, string) {
+	t.Helper()
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR not set")
+	}
+	c := NewRedisCache(addr)
+	ctx := context.Background()
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		t.Skipf("Redis at %s: %v", addr, err)
+	}
+	namespace := uuid.NewString()
+	t.Cleanup(func() {
+		keys, _ := c.client.Keys(ctx, "ignis:kv:{"+namespace+"}:*").Result()
+		if len(keys) > 0 {
+			c.client.Del(ctx, keys...)
+		}
+		c.client.Close()
+	})
+	return c.KV(maxKeys, maxValueBytes), namespace
+}
+
+func TestRedisKVKeyQuota(t *testing.T) {
+	s, ns := testKV(t, 2, 16)
+	ctx := context.Background()
+
+	if err := s.Set(ctx, ns, "a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, ns, "b", []byte("2"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, ns, "c", []byte("3"), 0); !errors.Is(err, kv.ErrQuotaExceeded) {
+		t.Fatalf("Set of a third key = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := s.Incr(ctx, ns, "c", 1); !errors.Is(err, kv.ErrQuotaExceeded) {
+		t.Fatalf("Incr of a third key = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := s.Get(ctx, ns, "c"); !errors.Is(err, kv.ErrNotFound) {
+		t.Fatalf("refused key was stored: %v", err)
+	}
+
+	// Existing keys can still be written
+	if err := s.Set(ctx, ns, "a", []byte("10"), 0); err != nil {
+		t.Fatalf("overwriting a key at quota: %v", err)
+	}
+	if n, err := s.Incr(ctx, ns, "a", 5); err != nil || n != 15 {
+		t.Fatalf("Incr of a key at quota = %d, %v, want 15", n, err)
+	}
+
+	// Deleting one makes room
+	if deleted, err := s.Delete(ctx, ns, "b"); err != nil || !deleted {
+		t.Fatalf("Delete = %v, %v", deleted, err)
+	}
+	if n, err := s.Incr(ctx, ns, "c", 3); err != nil || n != 3 {
+		t.Fatalf("Incr after Delete = %d, %v, want 3", n, err)
+	}
+	if keys, err := s.List(ctx, ns, ""); err != nil || !slices.Equal(keys, []string{"a", "c"}) {
+		t.Fatalf("List = %v, %v, want [a c]", keys, err)
+	}
+
+	// Other namespaces have their own quota
+	if err := s.Set(ctx, ns+"-other", "a", []byte("1"), 0); err != nil {
+		t.Fatalf("Set in another namespace: %v", err)
+	}
+	t.Cleanup(func() { s.Delete(ctx, ns+"-other", "a") })
+}
+
+func TestRedisKVExpiredKeysFreeQuota(t *testing.T) {
+	s, ns := testKV(t, 1, 16)
+	ctx := context.Background()
+
+	if err := s.Set(ctx, ns, "short", []byte("1"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, ns, "other", []byte("1"), 0); !errors.Is(err, kv.ErrQuotaExceeded) {
+		t.Fatalf("Set before expiry = %v, want ErrQuotaExceeded", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if keys, err := s.List(ctx, ns, ""); err != nil || len(keys) != 0 {
+		t.Fatalf("List after expiry = %v, %v, want none", keys, err)
+	}
+	if err := s.Set(ctx, ns, "other", []byte("1"), 0); err != nil {
+		t.Fatalf("Set after expiry: %v", err)
+	}
+}
+
+func TestRedisKVValueQuota(t *testing.T) {
+	s, ns := testKV(t, 10, 4)
+	ctx := context.Background()
+
+	if err := s.Set(ctx, ns, "a", []byte("12345"), 0); !errors.Is(err, kv.ErrQuotaExceeded) {
+		t.Fatalf("Set of an oversized value = %v, want ErrQuotaExceeded", err)
+	}
+	if err := s.Set(ctx, ns, "a", []byte("text"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Incr(ctx, ns, "a", 1); !errors.Is(err, kv.ErrNotInteger) {
+		t.Fatalf("Incr of text = %v, want ErrNotInteger", err)
+	}
+}
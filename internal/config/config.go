@@ -3,7 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -22,6 +24,91 @@ type Config struct {
 	S3SecretKey      string
 	S3BucketName     string
 	S3Region         string
+
+	// ExecutionTimeout is the wall-clock limit applied to deployments that
+	// do not configure their own.
+	ExecutionTimeout time.Duration
+
+	// FuelBudget is the fuel granted to each execution of deployments that
+	// do not configure their own budget.
+	FuelBudget uint64
+
+	// Default store limits for deployments that do not configure their own.
+	// Zero keeps Wasmtime's default.
+	MaxMemoryBytes   int64
+	MaxTableElements int64
+	MaxInstances     int64
+	MaxMemories      int64
+
+	// ModuleCacheBytes bounds the compiled modules kept in process memory.
+	ModuleCacheBytes int64
+
+	// PoolingAllocator switches engines to Wasmtime's pooling allocator,
+	// sized by the Pooling* settings. Zero keeps Wasmtime's default.
+	PoolingAllocator     bool
+	PoolingMaxInstances  uint32
+	PoolingMemoryPages   uint64
+	PoolingTableElements uint64
+
+	// GuestLogMaxBytes caps the stderr, and separately the host_log
+	// records, kept from a single execution, and GuestLogHistory is how
+	// many executions' logs are kept per deployment, for at most
+	// GuestLogDeployments deployments.
+	GuestLogMaxBytes    int
+	GuestLogHistory     int
+	GuestLogDeployments int
+	// GuestLogRate and GuestLogBurst bound the records a single execution
+	// emits with host_log: GuestLogBurst at once, then GuestLogRate per
+	// second.
+	GuestLogRate  int
+	GuestLogBurst int
+
+	// EncryptionKey is the base64-encoded 32-byte key encrypting deployment
+	// secrets at rest. Secrets are rejected when it is empty.
+	EncryptionKey string
+
+	// SandboxRoot is the host directory that deployments' preopened
+	// directories must lie within. Preopened directories are rejected when
+	// it is empty.
+	SandboxRoot string
+
+	// WorkspaceRoot is the host directory holding deployments' private
+	// workspaces, and WorkspaceQuotaBytes the size allowed to deployments
+	// that do not configure their own quota. Zero disables the quota.
+	WorkspaceRoot       string
+	WorkspaceQuotaBytes int64
+
+	// Defaults for guests' outbound HTTP requests, for deployments that do
+	// not configure their own. HTTPConnectTimeout applies to every deployment.
+	HTTPConnectTimeout time.Duration
+	HTTPTimeout        time.Duration
+	HTTPMaxBodyBytes   int64
+	HTTPMaxRedirects   int64
+
+	// NetworkCacheDeployments bounds the deployments whose HTTP clients,
+	// and their idle connections, are kept between executions.
+	NetworkCacheDeployments int
+
+	// TCP deployments listen on TCPListenHost, on a port between TCPPortMin
+	// and TCPPortMax, and serve at most TCPMaxConnections connections at
+	// once each.
+	TCPListenHost     string
+	TCPPortMin        int
+	TCPPortMax        int
+	TCPMaxConnections int
+
+	// Each deployment's key-value namespace holds at most KVMaxKeys keys of
+	// up to KVMaxValueBytes each.
+	KVMaxKeys       int
+	KVMaxValueBytes int
+
+	// Each deployment may create at most GuestMetricMaxSeries series with
+	// host_metric.
+	GuestMetricMaxSeries int
+
+	// Calls between deployments with host_invoke nest at most
+	// InvokeMaxDepth deep.
+	InvokeMaxDepth int
 }
 
 var (
@@ -53,6 +140,49 @@ func GetConfig() *Config {
 			S3SecretKey:      getEnv("S3_SECRET_KEY", ""),
 			S3BucketName:     getEnv("S3_BUCKET_NAME", ""),
 			S3Region:         getEnv("S3_REGION", "auto"),
+			ExecutionTimeout: getEnvDuration("EXECUTION_TIMEOUT", 30*time.Second),
+			FuelBudget:       getEnvUint64("FUEL_BUDGET", 10_000_000_000),
+			MaxMemoryBytes:   int64(getEnvUint64("MAX_MEMORY_BYTES", 256<<20)),
+			MaxTableElements: int64(getEnvUint64("MAX_TABLE_ELEMENTS", 0)),
+			MaxInstances:     int64(getEnvUint64("MAX_INSTANCES", 0)),
+			MaxMemories:      int64(getEnvUint64("MAX_MEMORIES", 0)),
+			ModuleCacheBytes: int64(getEnvUint64("MODULE_CACHE_BYTES", 512<<20)),
+
+			PoolingAllocator:     getEnvBool("POOLING_ALLOCATOR", false),
+			PoolingMaxInstances:  uint32(getEnvUint64("POOLING_MAX_INSTANCES", 1000)),
+			PoolingMemoryPages:   getEnvUint64("POOLING_MEMORY_PAGES", 4096),
+			PoolingTableElements: getEnvUint64("POOLING_TABLE_ELEMENTS", 65536),
+
+			GuestLogMaxBytes:    int(getEnvUint64("GUEST_LOG_MAX_BYTES", 64<<10)),
+			GuestLogHistory:     int(getEnvUint64("GUEST_LOG_HISTORY", 100)),
+			GuestLogDeployments: int(getEnvUint64("GUEST_LOG_DEPLOYMENTS", 1000)),
+			GuestLogRate:        int(getEnvUint64("GUEST_LOG_RATE", 50)),
+			GuestLogBurst:       int(getEnvUint64("GUEST_LOG_BURST", 100)),
+
+			EncryptionKey: getEnv("ENCRYPTION_KEY", ""),
+			SandboxRoot:   getEnv("SANDBOX_ROOT", ""),
+
+			WorkspaceRoot:       getEnv("WORKSPACE_ROOT", "./workspaces"),
+			WorkspaceQuotaBytes: int64(getEnvUint64("WORKSPACE_QUOTA_BYTES", 64<<20)),
+
+			HTTPConnectTimeout: getEnvDuration("HTTP_CONNECT_TIMEOUT", 5*time.Second),
+			HTTPTimeout:        getEnvDuration("HTTP_TIMEOUT", 30*time.Second),
+			HTTPMaxBodyBytes:   int64(getEnvUint64("HTTP_MAX_BODY_BYTES", 10<<20)),
+			HTTPMaxRedirects:   int64(getEnvUint64("HTTP_MAX_REDIRECTS", 10)),
+
+			NetworkCacheDeployments: int(getEnvUint64("NETWORK_CACHE_DEPLOYMENTS", 1000)),
+
+			TCPListenHost:     getEnv("TCP_LISTEN_HOST", ""),
+			TCPPortMin:        int(getEnvUint64("TCP_PORT_MIN", 30000)),
+			TCPPortMax:        int(getEnvUint64("TCP_PORT_MAX", 30999)),
+			TCPMaxConnections: int(getEnvUint64("TCP_MAX_CONNECTIONS", 100)),
+
+			KVMaxKeys:       int(getEnvUint64("KV_MAX_KEYS", 1000)),
+			KVMaxValueBytes: int(getEnvUint64("KV_MAX_VALUE_BYTES", 64<<10)),
+
+			GuestMetricMaxSeries: int(getEnvUint64("GUEST_METRIC_MAX_SERIES", 100)),
+
+			InvokeMaxDepth: int(getEnvUint64("INVOKE_MAX_DEPTH", 4)),
 		}
 	})
 	return instance
@@ -65,3 +195,48 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration parses a duration environment variable (e.g. "30s"),
+// falling back to the default when it is unset or invalid.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s: %q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvUint64 parses an unsigned integer environment variable, falling back
+// to the default when it is unset or invalid.
+func getEnvUint64(key string, defaultValue uint64) uint64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Printf("Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvBool parses a boolean environment variable (e.g. "true", "1"),
+// falling back to the default when it is unset or invalid.
+func getEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s: %q, using default %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
@@ -0,0 +1,40 @@
+// Package kv defines the key-value storage behind the host_kv functions. It
+// has no dependencies, so stores can implement it without linking Wasmtime.
+package kv
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound means the key doesn't exist or has expired.
+	ErrNotFound = errors.New("key not found")
+	// ErrQuotaExceeded means the write would take the namespace over its
+	// key count or value size quota.
+	ErrQuotaExceeded = errors.New("key-value quota exceeded")
+	// ErrNotInteger means an increment hit a value that isn't an integer.
+	ErrNotInteger = errors.New("value is not an integer")
+	// ErrUnavailable means there is no store, or it can't be reached.
+	// Stores wrap their connection failures in it.
+	ErrUnavailable = errors.New("key-value store unavailable")
+)
+
+// Store is the storage behind the host_kv functions. Every key lives in a
+// namespace, which the host functions set to the deployment ID, so a guest
+// never sees another deployment's keys. Implementations enforce their own
+// quotas and must be safe for concurrent use.
+type Store interface {
+	// Get returns the value of key, or ErrNotFound.
+	Get(ctx context.Context, namespace, key string) ([]byte, error)
+	// Set stores value under key, expiring after ttl if it is positive.
+	Set(ctx context.Context, namespace, key string, value []byte, ttl time.Duration) error
+	// Delete removes key, reporting whether it existed.
+	Delete(ctx context.Context, namespace, key string) (bool, error)
+	// Incr atomically adds delta to the integer under key, starting from 0,
+	// and returns the result.
+	Incr(ctx context.Context, namespace, key string, delta int64) (int64, error)
+	// List returns the keys starting with prefix, sorted.
+	List(ctx context.Context, namespace, prefix string) ([]string, error)
+}
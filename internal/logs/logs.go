@@ -0,0 +1,131 @@
+package logs
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry is the guest stderr and structured records captured from one
+// execution.
+type Entry struct {
+	Time           time.Time
+	DeploymentID   uuid.UUID
+	RequestID      string
+	Stderr         string
+	Truncated      bool
+	Records        []Record
+	RecordsDropped int
+}
+
+// Record is a structured record a guest emitted with host_log.
+type Record struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Fields  map[string]string
+}
+
+// Store keeps the most recent entries of each deployment in memory, for the
+// deployments that logged most recently. It is safe for concurrent use.
+type Store struct {
+	mu          sync.Mutex
+	history     int
+	deployments int
+	// order lists the deployments with entries, most recently appended to
+	// first.
+	order   *list.List
+	entries map[uuid.UUID]*list.Element
+}
+
+type storeEntry struct {
+	deploymentID uuid.UUID
+	entries      []Entry
+}
+
+// NewStore creates a store keeping at most history entries per deployment,
+// for at most deployments deployments. Once full, the deployment appended to
+// least recently is dropped to make room for another.
+func NewStore(history, deployments int) *Store {
+	return &Store{
+		history:     history,
+		deployments: deployments,
+		order:       list.New(),
+		entries:     make(map[uuid.UUID]*list.Element),
+	}
+}
+
+// Append records entry, dropping the deployment's oldest entry once its
+// history is full.
+func (s *Store) Append(entry Entry) {
+	if s.history <= 0 || s.deployments <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[entry.DeploymentID]
+	if ok {
+		s.order.MoveToFront(elem)
+	} else {
+		elem = s.order.PushFront(&storeEntry{deploymentID: entry.DeploymentID})
+		s.entries[entry.DeploymentID] = elem
+		for s.order.Len() > s.deployments {
+			s.remove(s.order.Back())
+		}
+	}
+	e := elem.Value.(*storeEntry)
+	entries := append(e.entries, entry)
+	if len(entries) > s.history {
+		entries = append(entries[:0:0], entries[len(entries)-s.history:]...)
+	}
+	e.entries = entries
+}
+
+// Forget drops every entry of the deployment, for when it is deleted.
+func (s *Store) Forget(deploymentID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[deploymentID]; ok {
+		s.remove(elem)
+	}
+}
+
+func (s *Store) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*storeEntry).deploymentID)
+}
+
+// Recent returns up to limit entries of the deployment, newest first. A
+// limit of zero or less returns every retained entry.
+func (s *Store) Recent(deploymentID uuid.UUID, limit int) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []Entry
+	if elem, ok := s.entries[deploymentID]; ok {
+		entries = elem.Value.(*storeEntry).entries
+	}
+	if limit <= 0 || limit > len(entries) {
+		limit = len(entries)
+	}
+	recent := make([]Entry, 0, limit)
+	for i := len(entries) - 1; i >= len(entries)-limit; i-- {
+		recent = append(recent, entries[i])
+	}
+	return recent
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
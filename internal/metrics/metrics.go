@@ -0,0 +1,287 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metric families and renders them in the Prometheus text format.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Default is the registry exposed by Handler.
+var Default = NewRegistry()
+
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	// buckets are the upper bounds of a histogram's buckets, in increasing
+	// order, without +Inf.
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one labelled value of a family. For a histogram, value is the
+// sum of the observations, counts the cumulative bucket counts and count the
+// number of observations.
+type series struct {
+	labelNames  []string
+	labelValues []string
+	value       float64
+	counts      []uint64
+	count       uint64
+}
+
+func (r *Registry) register(name, help, kind string, labels []string) *family {
+	return r.registerFamily(&family{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series),
+	})
+}
+
+// registerFamily adds f to the registry, unless a family of the same name
+// exists, and returns the registered family.
+func (r *Registry) registerFamily(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.families[f.name]; ok {
+		return existing
+	}
+	r.families[f.name] = f
+	return f
+}
+
+// unregister removes f from the registry, if it is still registered.
+func (r *Registry) unregister(f *family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.families[f.name] == f {
+		delete(r.families, f.name)
+	}
+}
+
+// add applies fn to the series identified by labelValues, creating it if needed.
+func (f *family) add(labelValues []string, fn func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	f.addSeries(f.labels, labelValues, fn)
+}
+
+// addSeries is add for a series whose label names may differ from the
+// family's.
+func (f *family) addSeries(labelNames, labelValues []string, fn func(*series)) {
+	key := strings.Join(labelNames, "\xff") + "\xfe" + strings.Join(labelValues, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labelNames:  append([]string(nil), labelNames...),
+			labelValues: append([]string(nil), labelValues...),
+		}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// removeSeries deletes the series of key and returns how many series are
+// left.
+func (f *family) removeSeries(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.series, key)
+	return len(f.series)
+}
+
+// observe adds v to a histogram series.
+func (f *family) observe(s *series, v float64) {
+	for i, upper := range f.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += v
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+type CounterVec struct {
+	f *family
+}
+
+// NewCounterVec registers a counter on the default registry.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: Default.register(name, help, "counter", labels)}
+}
+
+// Inc increments the counter identified by labelValues by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter identified by labelValues by v. Negative values are ignored.
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.f.add(labelValues, func(s *series) { s.value += v })
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct {
+	f *family
+}
+
+// NewGaugeVec registers a gauge on the default registry.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: Default.register(name, help, "gauge", labels)}
+}
+
+// Set sets the gauge identified by labelValues to v.
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.f.add(labelValues, func(s *series) { s.value = v })
+}
+
+// Add adds v (which may be negative) to the gauge identified by labelValues.
+func (g *GaugeVec) Add(v float64, labelValues ...string) {
+	g.f.add(labelValues, func(s *series) { s.value += v })
+}
+
+// DefBuckets are the default histogram buckets, suited to durations in
+// seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramVec counts observations in buckets, partitioned by labels.
+type HistogramVec struct {
+	f *family
+}
+
+// NewHistogramVec registers a histogram on the default registry. A nil
+// buckets uses DefBuckets.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{f: Default.registerHistogram(name, help, buckets, labels)}
+}
+
+// Observe adds v to the histogram identified by labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	h.f.add(labelValues, func(s *series) { h.f.observe(s, v) })
+}
+
+// registerHistogram is register for a histogram family. A nil buckets uses
+// DefBuckets.
+func (r *Registry) registerHistogram(name, help string, buckets []float64, labels []string) *family {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return r.registerFamily(&family{
+		name:    name,
+		help:    help,
+		kind:    "histogram",
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	})
+}
+
+// WriteTo renders every family in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make([]*family, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		families = append(families, r.families[name])
+	}
+	r.mu.RUnlock()
+
+	var b strings.Builder
+	for _, f := range families {
+		f.writeTo(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (f *family) writeTo(b *strings.Builder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, f.help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, formatLabels(s.labelNames, s.labelValues), formatValue(s.value))
+			continue
+		}
+		for i, upper := range f.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(s.labelNames, s.labelValues, "le", formatValue(upper)), s.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, formatLabels(s.labelNames, s.labelValues, "le", "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, formatLabels(s.labelNames, s.labelValues), formatValue(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, formatLabels(s.labelNames, s.labelValues), s.count)
+	}
+}
+
+// formatLabels renders the label pairs of names and values, followed by the
+// name/value pairs in extra.
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		pairs = append(pairs, name+"="+strconv.Quote(values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Handler serves the default registry.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = Default.WriteTo(w)
+	})
+}
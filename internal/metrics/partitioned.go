@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxSampleNameBytes bounds the length of a sample's name.
+	maxSampleNameBytes = 64
+	// maxSampleLabels bounds the labels of a sample.
+	maxSampleLabels = 8
+	// maxLabelValueBytes bounds the length of a label value.
+	maxLabelValueBytes = 128
+)
+
+var (
+	// ErrInvalidSample means a sample's kind, name, labels or value can't be
+	// recorded. Errors returned by Partitioned.Record wrap it with the reason.
+	ErrInvalidSample = errors.New("invalid sample")
+	// ErrTooManySeries means recording a sample would take its partition over
+	// its series limit.
+	ErrTooManySeries = errors.New("too many series")
+)
+
+var metricName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Sample is a value recorded into a Partitioned.
+type Sample struct {
+	// Kind is "counter", "gauge" or "histogram".
+	Kind string
+	// Name is appended to the Partitioned's prefix to name the family.
+	Name   string
+	Labels map[string]string
+	// Value is added to a counter, set on a gauge and observed by a
+	// histogram.
+	Value float64
+	// Delta makes a gauge sample add Value instead of setting it.
+	Delta bool
+}
+
+// Partitioned holds metrics recorded on behalf of untrusted code, such as
+// guests. Families are registered on the default registry on first use,
+// named by a common prefix, the kind and the sample's name, so partitions
+// can't claim a name with a kind another partition needs. Every series
+// carries the label naming its partition, and each partition may create at
+// most a fixed number of series, which also bounds the families it adds, so
+// it can't grow the registry without bound. It is safe for concurrent use.
+type Partitioned struct {
+	prefix    string
+	help      string
+	label     string
+	maxSeries int
+
+	mu sync.Mutex
+	// series holds the series of each partition, by key.
+	series map[string]map[string]seriesRef
+}
+
+// seriesRef locates a series in its family.
+type seriesRef struct {
+	f   *family
+	key string
+}
+
+// NewPartitioned creates a set of metrics named prefix followed by the
+// samples' kinds and names, described by help and partitioned by label,
+// with at most maxSeries series per partition.
+func NewPartitioned(prefix, help, label string, maxSeries int) *Partitioned {
+	return &Partitioned{
+		prefix:    prefix,
+		help:      help,
+		label:     label,
+		maxSeries: maxSeries,
+		series:    make(map[string]map[string]seriesRef),
+	}
+}
+
+// Record records sample for partition. It returns an error wrapping
+// ErrInvalidSample, or ErrTooManySeries if the sample would start a new
+// series past the partition's limit; samples for existing series are always
+// recorded. A family is only registered once its first series is admitted.
+func (p *Partitioned) Record(partition string, sample Sample) error {
+	labelNames, labelValues, err := p.checkSample(sample)
+	if err != nil {
+		return err
+	}
+	name := p.prefix + sample.Kind + "_" + sample.Name
+	labelNames = append([]string{p.label}, labelNames...)
+	labelValues = append([]string{partition}, labelValues...)
+	key := strings.Join(labelNames, "\xff") + "\xfe" + strings.Join(labelValues, "\xff")
+
+	// The lock is held until the series exists, so Drop can't remove the
+	// family in between.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys, ok := p.series[partition]
+	if !ok {
+		keys = make(map[string]seriesRef)
+		p.series[partition] = keys
+	}
+	ref, ok := keys[name+"\xfe"+key]
+	if !ok {
+		if len(keys) >= p.maxSeries {
+			return ErrTooManySeries
+		}
+		var f *family
+		if sample.Kind == "histogram" {
+			f = Default.registerHistogram(name, p.help, nil, nil)
+		} else {
+			f = Default.register(name, p.help, sample.Kind, nil)
+		}
+		ref = seriesRef{f: f, key: key}
+		keys[name+"\xfe"+key] = ref
+	}
+
+	f := ref.f
+	f.addSeries(labelNames, labelValues, func(s *series) {
+		switch {
+		case sample.Kind == "histogram":
+			f.observe(s, sample.Value)
+		case sample.Kind == "gauge" && !sample.Delta:
+			s.value = sample.Value
+		default:
+			s.value += sample.Value
+		}
+	})
+	return nil
+}
+
+// Drop removes every series of partition, and the families left without
+// series, for when the partition's owner is deleted.
+func (p *Partitioned) Drop(partition string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ref := range p.series[partition] {
+		if ref.f.removeSeries(ref.key) == 0 {
+			Default.unregister(ref.f)
+		}
+	}
+	delete(p.series, partition)
+}
+
+// checkSample validates sample and returns its label names, sorted, and
+// their values.
+func (p *Partitioned) checkSample(sample Sample) ([]string, []string, error) {
+	switch sample.Kind {
+	case "counter", "gauge", "histogram":
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidSample, sample.Kind)
+	}
+	if len(sample.Name) > maxSampleNameBytes || !metricName.MatchString(sample.Name) {
+		return nil, nil, fmt.Errorf("%w: invalid name %q", ErrInvalidSample, sample.Name)
+	}
+	if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+		return nil, nil, fmt.Errorf("%w: value must be finite", ErrInvalidSample)
+	}
+	if sample.Kind == "counter" && sample.Value < 0 {
+		return nil, nil, fmt.Errorf("%w: counters can't decrease", ErrInvalidSample)
+	}
+	if len(sample.Labels) > maxSampleLabels {
+		return nil, nil, fmt.Errorf("%w: more than %d labels", ErrInvalidSample, maxSampleLabels)
+	}
+
+	names := make([]string, 0, len(sample.Labels))
+	for name, value := range sample.Labels {
+		if !metricName.MatchString(name) || strings.HasPrefix(name, "__") || name == p.label || name == "le" {
+			return nil, nil, fmt.Errorf("%w: invalid label name %q", ErrInvalidSample, name)
+		}
+		if len(value) > maxLabelValueBytes || !printable(value) {
+			return nil, nil, fmt.Errorf("%w: invalid value for label %s", ErrInvalidSample, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = sample.Labels[name]
+	}
+	return names, values, nil
+}
+
+// printable reports whether s is valid UTF-8 made of printable characters,
+// which formatLabels renders as is.
+func printable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// render returns the lines of the default registry mentioning prefix.
+func render(t *testing.T, prefix string) []string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := Default.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestPartitionedSeriesLimit(t *testing.T) {
+	const prefix = "test_limit_"
+	p := NewPartitioned(prefix, "Test metrics.", "tenant", 3)
+	for i := range 3 {
+		sample := Sample{Kind: "counter", Name: "hits", Labels: map[string]string{"path": fmt.Sprint(i)}, Value: 1}
+		if err := p.Record("a", sample); err != nil {
+			t.Fatalf("recording series %d: %v", i, err)
+		}
+	}
+
+	// New series past the limit are refused, in any family
+	if err := p.Record("a", Sample{Kind: "counter", Name: "hits", Labels: map[string]string{"path": "3"}, Value: 1}); !errors.Is(err, ErrTooManySeries) {
+		t.Errorf("fourth series: %v, want ErrTooManySeries", err)
+	}
+	if err := p.Record("a", Sample{Kind: "gauge", Name: "other", Value: 1}); !errors.Is(err, ErrTooManySeries) {
+		t.Errorf("series of a new family: %v, want ErrTooManySeries", err)
+	}
+	if lines := render(t, prefix+"gauge_other"); len(lines) != 0 {
+		t.Errorf("refused family registered: %q", lines)
+	}
+
+	// Existing series are still recorded, and other partitions have their own limit
+	if err := p.Record("a", Sample{Kind: "counter", Name: "hits", Labels: map[string]string{"path": "0"}, Value: 2}); err != nil {
+		t.Errorf("existing series: %v", err)
+	}
+	if err := p.Record("b", Sample{Kind: "counter", Name: "hits", Value: 1}); err != nil {
+		t.Errorf("another partition: %v", err)
+	}
+
+	lines := render(t, prefix+"counter_hits{")
+	want := map[string]bool{
+		`test_limit_counter_hits{tenant="a",path="0"} 3`: true,
+		`test_limit_counter_hits{tenant="a",path="1"} 1`: true,
+		`test_limit_counter_hits{tenant="a",path="2"} 1`: true,
+		`test_limit_counter_hits{tenant="b"} 1`:          true,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("rendered %q", lines)
+	}
+	for _, line := range lines {
+		if !want[line] {
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestPartitionedDrop(t *testing.T) {
+	const prefix = "test_drop_"
+	p := NewPartitioned(prefix, "Test metrics.", "tenant", 10)
+	for _, tenant := range []string{"a", "b"} {
+		if err := p.Record(tenant, Sample{Kind: "counter", Name: "shared", Value: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Record("a", Sample{Kind: "histogram", Name: "own", Value: 0.2}); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Drop("a")
+	lines := render(t, prefix)
+	if len(lines) != 1 || lines[0] != `test_drop_counter_shared{tenant="b"} 1` {
+		t.Fatalf("after Drop: %q", lines)
+	}
+
+	// The dropped partition starts over with its full limit
+	if err := p.Record("a", Sample{Kind: "histogram", Name: "own", Value: 0.2}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := render(t, prefix+"histogram_own_count"); len(lines) != 1 || !strings.HasSuffix(lines[0], " 1") {
+		t.Errorf("recreated histogram: %q", lines)
+	}
+}
+
+func TestPartitionedInvalidSample(t *testing.T) {
+	p := NewPartitioned("test_invalid_", "Test metrics.", "tenant", 10)
+	tests := []struct {
+		name   string
+		sample Sample
+	}{
+		{"unknown kind", Sample{Kind: "summary", Name: "x"}},
+		{"empty name", Sample{Kind: "counter"}},
+		{"bad name", Sample{Kind: "counter", Name: "a-b"}},
+		{"long name", Sample{Kind: "counter", Name: strings.Repeat("a", maxSampleNameBytes+1)}},
+		{"NaN", Sample{Kind: "gauge", Name: "x", Value: math.NaN()}},
+		{"infinite", Sample{Kind: "gauge", Name: "x", Value: math.Inf(1)}},
+		{"decreasing counter", Sample{Kind: "counter", Name: "x", Value: -1}},
+		{"partition label", Sample{Kind: "counter", Name: "x", Labels: map[string]string{"tenant": "b"}}},
+		{"reserved label", Sample{Kind: "counter", Name: "x", Labels: map[string]string{"__name__": "y"}}},
+		{"le label", Sample{Kind: "histogram", Name: "x", Labels: map[string]string{"le": "1"}}},
+		{"unprintable value", Sample{Kind: "counter", Name: "x", Labels: map[string]string{"k": "a\nb"}}},
+		{"invalid UTF-8", Sample{Kind: "counter", Name: "x", Labels: map[string]string{"k": "\xff"}}},
+		{"long value", Sample{Kind: "counter", Name: "x", Labels: map[string]string{"k": strings.Repeat("v", maxLabelValueBytes+1)}}},
+	}
+	for _, tt := range tests {
+		if err := p.Record("a", tt.sample); !errors.Is(err, ErrInvalidSample) {
+			t.Errorf("%s: %v, want ErrInvalidSample", tt.name, err)
+		}
+	}
+
+	labels := make(map[string]string)
+	for i := range maxSampleLabels + 1 {
+		labels[fmt.Sprintf("l%d", i)] = "v"
+	}
+	if err := p.Record("a", Sample{Kind: "counter", Name: "x", Labels: labels}); !errors.Is(err, ErrInvalidSample) {
+		t.Errorf("too many labels: %v, want ErrInvalidSample", err)
+	}
+}
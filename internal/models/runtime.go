@@ -1,17 +1,143 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Runtime represents a deployed runtime in the system
+// Runtime represents a deployed runtime in the system. Env holds the guest's
+// plain environment variables; Secrets holds further variables whose values
+// are encrypted with internal/secrets and are never serialized. HostSecrets
+// are encrypted the same way but never enter the environment; the guest
+// reads them with host_secret_get. Kind is
+// "http" for deployments invoked through the run endpoint, or "tcp" for
+// those serving connections accepted on ListenPort.
 type Runtime struct {
-	ID            uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
-	RuntimeType   string      `json:"runtime_type" gorm:"not null"`
-	Hash          string      `json:"hash" gorm:"not null;index"`
-	S3FilePath    string      `json:"s3_file_path" gorm:"column:s3_file_path;not null"`
-	CreatedAt     time.Time   `json:"created_at"`
-	UpdatedAt     time.Time   `json:"updated_at"`
-}
\ No newline at end of file
+	ID               uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
+	RuntimeType      string      `json:"runtime_type" gorm:"not null"`
+	Hash             string      `json:"hash" gorm:"not null;index"`
+	S3FilePath       string      `json:"s3_file_path" gorm:"column:s3_file_path;not null"`
+	TimeoutMs        int64       `json:"timeout_ms" gorm:"not null;default:0"`
+	FuelBudget       int64       `json:"fuel_budget" gorm:"not null;default:0"`
+	MaxMemoryBytes   int64       `json:"max_memory_bytes" gorm:"not null;default:0"`
+	MaxTableElements int64       `json:"max_table_elements" gorm:"not null;default:0"`
+	MaxInstances     int64       `json:"max_instances" gorm:"not null;default:0"`
+	MaxMemories      int64       `json:"max_memories" gorm:"not null;default:0"`
+	PreopenedDir     string      `json:"preopened_dir" gorm:"not null;default:''"`
+	Args             StringSlice `json:"args" gorm:"type:jsonb;not null;default:'[]'"`
+	Env              StringMap   `json:"env" gorm:"type:jsonb;not null;default:'{}'"`
+	Secrets          StringMap   `json:"-" gorm:"type:jsonb;not null;default:'{}'"`
+	HostSecrets      StringMap   `json:"-" gorm:"type:jsonb;not null;default:'{}'"`
+	WorkspaceMode    string      `json:"workspace_mode" gorm:"not null;default:'ephemeral'"`
+	WorkspaceQuota   int64       `json:"workspace_quota" gorm:"not null;default:0"`
+	Egress           EgressRules `json:"egress" gorm:"type:jsonb;not null;default:'{}'"`
+	HTTPTimeoutMs    int64       `json:"http_timeout_ms" gorm:"column:http_timeout_ms;not null;default:0"`
+	HTTPMaxBodyBytes int64       `json:"http_max_body" gorm:"column:http_max_body;not null;default:0"`
+	HTTPMaxRedirects int64       `json:"http_max_redirects" gorm:"column:http_max_redirects;not null;default:0"`
+	Kind             string      `json:"kind" gorm:"not null;default:'http'"`
+	ListenPort       int         `json:"listen_port" gorm:"not null;default:0;uniqueIndex:idx_runtimes_listen_port,where:listen_port > 0"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// StringMap is a string-to-string map persisted as a JSON object
+type StringMap map[string]string
+
+// Value implements driver.Valuer
+func (m StringMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (m *StringMap) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = StringMap{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for StringMap: %T", src)
+	}
+	return json.Unmarshal(data, m)
+}
+
+// StringSlice is a list of strings persisted as a JSON array
+type StringSlice []string
+
+// Value implements driver.Valuer
+func (s StringSlice) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (s *StringSlice) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*s = StringSlice{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for StringSlice: %T", src)
+	}
+	return json.Unmarshal(data, s)
+}
+
+// EgressRules lists the destinations a deployment's guests may connect to,
+// persisted as a JSON object. An empty policy allows public addresses only.
+type EgressRules struct {
+	Hosts []string `json:"hosts"`
+	CIDRs []string `json:"cidrs"`
+	Ports []int    `json:"ports"`
+}
+
+// Value implements driver.Valuer
+func (p EgressRules) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (p *EgressRules) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = EgressRules{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for EgressRules: %T", src)
+	}
+	return json.Unmarshal(data, p)
+}
@@ -2,20 +2,38 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
 
 	"github.com/ignis-runtime/ignis-wasmtime/internal/models"
 )
 
+// ErrListenPortTaken is returned by Create when another deployment already
+// listens on the record's port
+var ErrListenPortTaken = errors.New("listen port already taken")
+
+// listenPortIndex is the unique index on listen_port, see models.Runtime
+const listenPortIndex = "idx_runtimes_listen_port"
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
 // DeploymentRepository defines the interface for runtime persistence operations
 type DeploymentRepository interface {
 	Create(ctx context.Context, runtime *models.Runtime) (*models.Runtime, error)
 	FindByID(ctx context.Context, id uuid.UUID) (*models.Runtime, error)
 	FindByHash(ctx context.Context, hash string) (*models.Runtime, error)
+	FindByListenPort(ctx context.Context, port int) (*models.Runtime, error)
 	GetAll(ctx context.Context) ([]*models.Runtime, error)
 	Update(ctx context.Context, runtime *models.Runtime) error
+	// SetHostSecret stores an encrypted host secret, replacing any of the
+	// same name
+	SetHostSecret(ctx context.Context, id uuid.UUID, name, encrypted string) error
+	// DeleteHostSecret removes a host secret, reporting whether it existed
+	DeleteHostSecret(ctx context.Context, id uuid.UUID, name string) (bool, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
@@ -33,6 +51,10 @@ func NewDeploymentRepository(db *gorm.DB) DeploymentRepository {
 func (r *deploymentRepository) Create(ctx context.Context, runtime *models.Runtime) (*models.Runtime, error) {
 	// GORM's Create method updates the 'runtime' pointer with DB-generated fields
 	err := r.db.WithContext(ctx).Create(runtime).Error
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == listenPortIndex {
+		return nil, ErrListenPortTaken
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -57,10 +79,42 @@ func (r *deploymentRepository) FindByHash(ctx context.Context, hash string) (*mo
 	return &runtime, nil
 }
 
+func (r *deploymentRepository) FindByListenPort(ctx context.Context, port int) (*models.Runtime, error) {
+	var runtime models.Runtime
+	err := r.db.WithContext(ctx).First(&runtime, "listen_port = ?", port).Error
+	if err != nil {
+		return nil, err
+	}
+	return &runtime, nil
+}
+
 func (r *deploymentRepository) Update(ctx context.Context, runtime *models.Runtime) error {
 	return r.db.WithContext(ctx).Save(runtime).Error
 }
 
+// SetHostSecret updates the single JSON entry in place, so concurrent
+// changes to other secrets aren't lost
+func (r *deploymentRepository) SetHostSecret(ctx context.Context, id uuid.UUID, name, encrypted string) error {
+	result := r.db.WithContext(ctx).Model(&models.Runtime{}).Where("id = ?", id).
+		Update("host_secrets", gorm.Expr("host_secrets || jsonb_build_object(?::text, ?::text)", name, encrypted))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *deploymentRepository) DeleteHostSecret(ctx context.Context, id uuid.UUID, name string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Runtime{}).Where("id = ? AND host_secrets ->> ? IS NOT NULL", id, name).
+		Update("host_secrets", gorm.Expr("host_secrets - ?::text", name))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 func (r *deploymentRepository) GetAll(ctx context.Context) ([]*models.Runtime, error) {
 	var runtimes []*models.Runtime
 	err := r.db.WithContext(ctx).Find(&runtimes).Error
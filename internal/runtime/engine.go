@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+const (
+	// EpochTickInterval is how often the epoch ticker advances an engine's epoch.
+	// Execution deadlines are rounded up to a whole number of ticks.
+	EpochTickInterval = 10 * time.Millisecond
+
+	// DefaultExecutionTimeout bounds a single execution when neither the
+	// deployment nor the request context provides a deadline.
+	DefaultExecutionTimeout = 30 * time.Second
+
+	// DefaultFuelBudget is the fuel granted to an execution when the deployment
+	// does not configure its own budget.
+	DefaultFuelBudget uint64 = 10_000_000_000
+
+	// EngineConfigVersion identifies the configuration applied by NewEngine.
+	// It is part of the cache key of serialized modules and must be bumped
+	// whenever NewEngine changes, so stale artifacts are never deserialized.
+	EngineConfigVersion = "epoch-fuel-pooling-2"
+)
+
+// NewEngine creates a Wasmtime engine with epoch interruption and fuel
+// consumption enabled, so guests can be stopped once their execution deadline
+// passes or their CPU budget is spent. Instances are allocated on demand unless
+// pooling is enabled.
+//
+// Modules must be compiled and deserialized with engines built by this function,
+// since Wasmtime rejects artifacts produced under a different configuration.
+func NewEngine(pooling PoolingConfig) *wasmtime.Engine {
+	cfg := wasmtime.NewConfig()
+	cfg.SetEpochInterruption(true)
+	cfg.SetConsumeFuel(true)
+	if pooling.Enabled {
+		pooling.apply(cfg)
+	}
+	return wasmtime.NewEngineWithConfig(cfg)
+}
+
+// EpochTicker periodically increments the epoch of an engine.
+type EpochTicker struct {
+	done chan struct{}
+	once sync.Once
+}
+
+// StartEpochTicker starts a background goroutine advancing the engine's epoch
+// every EpochTickInterval until Stop is called.
+func StartEpochTicker(engine *wasmtime.Engine) *EpochTicker {
+	t := &EpochTicker{done: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(EpochTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				engine.IncrementEpoch()
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+// Stop halts the ticker. It is safe to call multiple times.
+func (t *EpochTicker) Stop() {
+	t.once.Do(func() { close(t.done) })
+}
+
+// epochTicks converts a timeout into the number of epoch ticks after which
+// execution is interrupted, rounding up so short timeouts still get one tick.
+func epochTicks(timeout time.Duration) uint64 {
+	ticks := (timeout + EpochTickInterval - 1) / EpochTickInterval
+	if ticks < 1 {
+		return 1
+	}
+	return uint64(ticks)
+}
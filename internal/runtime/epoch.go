@@ -0,0 +1,68 @@
+package runtime
+
+// The Go bindings only let a store trap once it reaches its epoch deadline.
+// libwasmtime can instead call back into the embedder, which then either
+// stops the guest or extends the deadline, so a running guest can be checked
+// on periodically. The prototypes below mirror store.h and error.h.
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct wasmtime_store wasmtime_store_t;
+typedef struct wasmtime_context wasmtime_context_t;
+typedef struct wasmtime_error wasmtime_error_t;
+
+extern void wasmtime_store_epoch_deadline_callback(wasmtime_store_t *store,
+	wasmtime_error_t *(*func)(wasmtime_context_t *, void *, uint64_t *, uint8_t *),
+	void *data, void (*finalizer)(void *));
+extern wasmtime_error_t *wasmtime_error_new(const char *message);
+
+extern wasmtime_error_t *ignisEpochDeadline(wasmtime_context_t *context, void *data,
+	uint64_t *delta, uint8_t *kind);
+extern void ignisReleaseHandle(void *data);
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+// epochStoppedMessage is the error Wasmtime reports for a guest stopped by an
+// epochCallback. The callback keeps the reason itself.
+const epochStoppedMessage = "execution stopped by the host"
+
+// epochCallback is called each time a store reaches its epoch deadline. It
+// returns how many ticks the guest may run until it is called again, or an
+// error to stop the guest.
+type epochCallback func() (uint64, error)
+
+// setEpochCallback makes the store call cb on reaching its epoch deadline
+// instead of trapping. cb is released once the store is closed.
+func setEpochCallback(store *wasmtime.Store, cb epochCallback) {
+	C.wasmtime_store_epoch_deadline_callback(storePtr(store),
+		(*[0]byte)(C.ignisEpochDeadline), newHandle(cb), (*[0]byte)(C.ignisReleaseHandle))
+}
+
+//export ignisEpochDeadline
+func ignisEpochDeadline(_ *C.wasmtime_context_t, data unsafe.Pointer, delta *C.uint64_t, kind *C.uint8_t) *C.wasmtime_error_t {
+	cb := cgo.Handle(*(*C.uintptr_t)(data)).Value().(epochCallback)
+	ticks, err := cb()
+	if err != nil {
+		msg := C.CString(epochStoppedMessage)
+		defer C.free(unsafe.Pointer(msg))
+		return C.wasmtime_error_new(msg)
+	}
+	*delta = C.uint64_t(ticks)
+	*kind = 0 // WASMTIME_UPDATE_DEADLINE_CONTINUE
+	return nil
+}
+
+// storePtr returns the wasmtime_store_t behind store. wasmtime.Store starts
+// with that pointer; this breaks if the bindings change its layout.
+func storePtr(store *wasmtime.Store) *C.wasmtime_store_t {
+	return (*struct{ ptr *C.wasmtime_store_t })(unsafe.Pointer(store)).ptr
+}
@@ -0,0 +1,47 @@
+package runtime
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+// ErrExecutionTimeout is returned when a guest is interrupted because its
+// execution deadline passed.
+var ErrExecutionTimeout = errors.New("execution timed out")
+
+// ErrFuelExhausted is returned when a guest runs out of its fuel budget.
+var ErrFuelExhausted = errors.New("execution budget exhausted")
+
+// Messages Wasmtime uses for these traps. They are matched when the trap is
+// reported without a trap code, e.g. wrapped in a *wasmtime.Error.
+const (
+	interruptTrapMessage = "wasm trap: interrupt"
+	outOfFuelTrapMessage = "wasm trap: all fuel consumed"
+)
+
+// classifyTrap maps Wasmtime traps raised by the host's own resource controls
+// to typed errors. Any other error is returned unchanged.
+func classifyTrap(err error) error {
+	var trap *wasmtime.Trap
+	if errors.As(err, &trap) {
+		if code := trap.Code(); code != nil {
+			switch *code {
+			case wasmtime.Interrupt:
+				return ErrExecutionTimeout
+			case wasmtime.OutOfFuel:
+				return ErrFuelExhausted
+			}
+		}
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, interruptTrapMessage):
+		return ErrExecutionTimeout
+	case strings.Contains(msg, outOfFuelTrapMessage):
+		return ErrFuelExhausted
+	}
+	return err
+}
@@ -0,0 +1,312 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
+)
+
+// Status codes returned by the host calls. A non-negative result is a length;
+// each failure of the call itself has its own negative code. Failures of the
+// requested operation, such as a refused connection, are reported inside the
+// response instead.
+const (
+	// ErrCodeMemory means the guest exports no memory, or a pointer and
+	// length don't lie within it.
+	ErrCodeMemory int32 = -1
+	// ErrCodeNoState means the store carries no host state.
+	ErrCodeNoState int32 = -2
+	// ErrCodeInvalidRequest means the request couldn't be decoded.
+	ErrCodeInvalidRequest int32 = -3
+	// ErrCodeInternal means the host failed to encode the response.
+	ErrCodeInternal int32 = -4
+	// ErrCodeBadHandle means the handle is unknown or was already read.
+	ErrCodeBadHandle int32 = -5
+	// ErrCodeBufferTooSmall means the buffer can't hold the response. The
+	// handle stays valid, so the guest can retry with a larger buffer.
+	ErrCodeBufferTooSmall int32 = -6
+	// ErrCodeTooManyPending means the session holds too many unread
+	// responses; the guest must read or drop some first.
+	ErrCodeTooManyPending int32 = -7
+	// ErrCodeIO means reading or writing a stream failed.
+	ErrCodeIO int32 = -8
+	// ErrCodeBadState means the call isn't valid at this point of a stream,
+	// such as writing a request body after reading the response.
+	ErrCodeBadState int32 = -9
+	// ErrCodeTooManyStreams means the session has too many open streams.
+	ErrCodeTooManyStreams int32 = -10
+	// ErrCodeNotFound means the key or secret doesn't exist.
+	ErrCodeNotFound int32 = -11
+	// ErrCodeQuotaExceeded means a write would exceed the deployment's
+	// key-value quotas, or a metric sample its series limit.
+	ErrCodeQuotaExceeded int32 = -12
+	// ErrCodeUnavailable means the key-value store, the secrets, the metrics
+	// or deployment invocation are not configured or can't be reached.
+	ErrCodeUnavailable int32 = -13
+	// ErrCodeNotInteger means an increment hit a value that isn't an integer.
+	ErrCodeNotInteger int32 = -14
+)
+
+// maxPendingResponses bounds the unread responses a session may hold.
+const maxPendingResponses = 16
+
+var errInvalidRequest = errors.New("invalid request")
+
+// hostCall handles one encoded request and returns the encoded response. Its
+// error is a failure of the call itself: errInvalidRequest, or anything else
+// for an internal failure.
+type hostCall func(state *State, req []byte) ([]byte, error)
+
+// PendingResponses holds the responses too large for the buffer a guest
+// passed, until the guest fetches them with host_response_read. Handles are
+// only meaningful within one session. It is safe for concurrent use.
+type PendingResponses struct {
+	mu         sync.Mutex
+	responses  map[int32][]byte
+	nextHandle int32
+}
+
+// NewPendingResponses creates an empty set. Handles start at 1, so 0 never
+// names a response.
+func NewPendingResponses() *PendingResponses {
+	return &PendingResponses{
+		responses:  make(map[int32][]byte),
+		nextHandle: 1,
+	}
+}
+
+// Add stores resp and returns its handle, or false if too many are pending.
+func (p *PendingResponses) Add(resp []byte) (int32, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.responses) >= maxPendingResponses {
+		return 0, false
+	}
+	handle := p.nextHandle
+	p.nextHandle++
+	p.responses[handle] = resp
+	return handle, true
+}
+
+// Get returns the response stored under handle.
+func (p *PendingResponses) Get(handle int32) ([]byte, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	resp, ok := p.responses[handle]
+	return resp, ok
+}
+
+// Remove discards the response stored under handle, reporting whether it
+// existed.
+func (p *PendingResponses) Remove(handle int32) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.responses[handle]
+	delete(p.responses, handle)
+	return ok
+}
+
+// Clear discards every pending response.
+func (p *PendingResponses) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responses = make(map[int32][]byte)
+}
+
+// guestMemory returns the memory exported by the calling guest.
+func guestMemory(caller *wasmtime.Caller) []byte {
+	export := caller.GetExport("memory")
+	if export == nil || export.Memory() == nil {
+		return nil
+	}
+	return export.Memory().UnsafeData(caller)
+}
+
+// guestSlice returns data[ptr:ptr+length], or false if that range isn't
+// within data.
+func guestSlice(data []byte, ptr, length int32) ([]byte, bool) {
+	if ptr < 0 || length < 0 || int64(ptr)+int64(length) > int64(len(data)) {
+		return nil, false
+	}
+	return data[ptr : ptr+length], true
+}
+
+// callErrorCode maps a failed hostCall to its status code.
+func callErrorCode(err error) int32 {
+	switch {
+	case errors.Is(err, errInvalidRequest):
+		return ErrCodeInvalidRequest
+	case errors.Is(err, kv.ErrNotFound), errors.Is(err, ErrSecretNotFound):
+		return ErrCodeNotFound
+	case errors.Is(err, kv.ErrQuotaExceeded):
+		return ErrCodeQuotaExceeded
+	case errors.Is(err, kv.ErrNotInteger):
+		return ErrCodeNotInteger
+	case errors.Is(err, kv.ErrUnavailable), errors.Is(err, ErrSecretsUnavailable), errors.Is(err, ErrInvokeUnavailable):
+		return ErrCodeUnavailable
+	default:
+		return ErrCodeInternal
+	}
+}
+
+// wrapCall exposes call with the two-phase ABI:
+//
+//	(reqPtr, reqLen, respPtr, respLen, handlePtr i32) -> i32
+//
+// The result is the length of the response, or a negative status code. If
+// the response fits in respLen bytes it is written at respPtr and the handle
+// at handlePtr is 0. Otherwise nothing is written at respPtr; the response is
+// kept under the handle written at handlePtr, and the guest fetches it with
+// host_response_read using a buffer of the returned length.
+func wrapCall(name string, call hostCall) func(*wasmtime.Caller, int32, int32, int32, int32, int32) int32 {
+	return func(caller *wasmtime.Caller, reqPtr, reqLen, respPtr, respLen, handlePtr int32) int32 {
+		data := guestMemory(caller)
+		reqBytes, ok := guestSlice(data, reqPtr, reqLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		// The buffers are checked up front, so a bad pointer fails before
+		// the call has any effect.
+		if _, ok := guestSlice(data, respPtr, respLen); !ok {
+			return ErrCodeMemory
+		}
+		if _, ok := guestSlice(data, handlePtr, 4); !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Printf("%s: store has no host state\n", name)
+			return ErrCodeNoState
+		}
+		// The request is copied, since the call may grow guest memory.
+		respBytes, err := call(state, append([]byte(nil), reqBytes...))
+		if err != nil {
+			// A missing key or secret is an answer, not a failure worth
+			// logging.
+			if callErrorCode(err) != ErrCodeNotFound {
+				log.Printf("%s: %v\n", name, err)
+			}
+			return callErrorCode(err)
+		}
+
+		return deliver(caller, state, respBytes, respPtr, respLen, handlePtr)
+	}
+}
+
+// deliver hands resp to the guest as described for wrapCall: written at
+// respPtr if it fits in respLen bytes, kept under a handle otherwise.
+func deliver(caller *wasmtime.Caller, state *State, resp []byte, respPtr, respLen, handlePtr int32) int32 {
+	// Memory is reacquired, since it may have grown since it was last read.
+	data := guestMemory(caller)
+	respBuf, ok := guestSlice(data, respPtr, respLen)
+	if !ok {
+		return ErrCodeMemory
+	}
+	handleBuf, ok := guestSlice(data, handlePtr, 4)
+	if !ok {
+		return ErrCodeMemory
+	}
+	var handle int32
+	if len(resp) <= len(respBuf) {
+		copy(respBuf, resp)
+	} else if handle, ok = state.Pending.Add(resp); !ok {
+		return ErrCodeTooManyPending
+	}
+	binary.LittleEndian.PutUint32(handleBuf, uint32(handle))
+	return int32(len(resp))
+}
+
+// wrapLegacyCall exposes call with the original ABI:
+//
+//	(reqPtr, reqLen, respPtr, respLen i32) -> i32
+//
+// The result is the length of the response written at respPtr, or 0 on any
+// failure, including a response larger than respLen. It is kept for guests
+// built against go-sdk v1.
+func wrapLegacyCall(name string, call hostCall) func(*wasmtime.Caller, int32, int32, int32, int32) int32 {
+	return func(caller *wasmtime.Caller, reqPtr, reqLen, respPtr, respLen int32) int32 {
+		// Without room for a handle the response can't be kept, so the
+		// legacy call writes it in full or fails.
+		data := guestMemory(caller)
+		reqBytes, ok := guestSlice(data, reqPtr, reqLen)
+		if !ok {
+			return 0
+		}
+		respBuf, ok := guestSlice(data, respPtr, respLen)
+		if !ok {
+			return 0
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Printf("%s: store has no host state\n", name)
+			return 0
+		}
+		respBytes, err := call(state, append([]byte(nil), reqBytes...))
+		if err != nil {
+			log.Printf("%s: %v\n", name, err)
+			return 0
+		}
+		if len(respBytes) > len(respBuf) {
+			log.Printf("%s: result buffer too small. Needed %d, have %d\n", name, len(respBytes), respLen)
+			return 0
+		}
+		copy(guestMemory(caller)[respPtr:], respBytes)
+		return int32(len(respBytes))
+	}
+}
+
+// LinkResponseFunctions attaches the functions fetching the responses of
+// two-phase host calls:
+//
+//	host_response_read(handle, bufPtr, bufLen i32) -> i32
+//	host_response_drop(handle i32) -> i32
+//
+// host_response_read copies the response into the buffer, releases the
+// handle and returns the length written. host_response_drop releases a
+// handle without reading it and returns 0. Both return a negative status
+// code on failure.
+func LinkResponseFunctions(linker *wasmtime.Linker) error {
+	err := linker.FuncWrap("env", "host_response_read", func(caller *wasmtime.Caller, handle, bufPtr, bufLen int32) int32 {
+		buf, ok := guestSlice(guestMemory(caller), bufPtr, bufLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_response_read: store has no host state")
+			return ErrCodeNoState
+		}
+		resp, ok := state.Pending.Get(handle)
+		if !ok {
+			return ErrCodeBadHandle
+		}
+		if len(resp) > len(buf) {
+			return ErrCodeBufferTooSmall
+		}
+		copy(buf, resp)
+		state.Pending.Remove(handle)
+		return int32(len(resp))
+	})
+	if err != nil {
+		return err
+	}
+
+	return linker.FuncWrap("env", "host_response_drop", func(caller *wasmtime.Caller, handle int32) int32 {
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_response_drop: store has no host state")
+			return ErrCodeNoState
+		}
+		if !state.Pending.Remove(handle) {
+			return ErrCodeBadHandle
+		}
+		return 0
+	})
+}
@@ -0,0 +1,226 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// EgressDeniedCode is the error code returned to guests whose outbound
+// connection was refused by their egress policy.
+const EgressDeniedCode = "egress_denied"
+
+// egressDialTimeout bounds how long establishing an outbound connection may take.
+const egressDialTimeout = 30 * time.Second
+
+// reservedPrefixes are ranges that aren't publicly routable but that net/netip
+// still counts as global unicast.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which may map to any IPv4 address
+}
+
+// EgressError reports an outbound connection refused by an EgressPolicy.
+type EgressError struct {
+	Address string
+	Reason  string
+}
+
+func (e *EgressError) Error() string {
+	return fmt.Sprintf("egress to %s denied: %s", e.Address, e.Reason)
+}
+
+// EgressPolicy decides which addresses a deployment's guests may connect to.
+//
+// Without allowed hosts or CIDRs every public address is reachable. Once
+// either is set, a destination must match an allowed host or resolve into an
+// allowed CIDR. Loopback, private, link-local and other non-public addresses
+// are always refused unless they fall inside an allowed CIDR, whatever the
+// host name, so a name resolving to an internal address is no way in.
+//
+// Addresses are checked as the connection is made, after DNS resolution, so
+// the check can't be bypassed by a name that resolves differently later.
+// When only hosts are allowed, other names aren't even looked up, since the
+// lookup alone would carry the name out to DNS servers.
+type EgressPolicy struct {
+	hosts []string
+	cidrs []netip.Prefix
+	ports map[int]bool
+}
+
+// NewEgressPolicy builds a policy. hosts are names such as "api.example.com",
+// or "*.example.com" for any subdomain; cidrs are networks such as
+// "10.1.0.0/16"; an empty ports list allows every port.
+func NewEgressPolicy(hosts, cidrs []string, ports []int) (*EgressPolicy, error) {
+	p := &EgressPolicy{}
+	for _, host := range hosts {
+		pattern := normalizeHost(host)
+		if name := strings.TrimPrefix(pattern, "*."); name == "" || strings.Contains(name, "*") {
+			return nil, fmt.Errorf("invalid egress host %q", host)
+		}
+		p.hosts = append(p.hosts, pattern)
+	}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress CIDR %q: %w", cidr, err)
+		}
+		p.cidrs = append(p.cidrs, prefix.Masked())
+	}
+	if len(ports) > 0 {
+		p.ports = make(map[int]bool, len(ports))
+		for _, port := range ports {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid egress port %d", port)
+			}
+			p.ports[port] = true
+		}
+	}
+	return p, nil
+}
+
+// DialContext connects to address if the policy allows it. Refusals are
+// returned as *EgressError, possibly wrapped in a *net.OpError.
+func (p *EgressPolicy) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := p.checkPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkName(address, host); err != nil {
+		return nil, err
+	}
+
+	hostAllowed := p.hostAllowed(host)
+	dialer := net.Dialer{
+		Timeout: egressDialTimeout,
+		Control: func(_, resolved string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(resolved)
+			if err != nil {
+				return &EgressError{Address: address, Reason: "unparseable address"}
+			}
+			return p.checkAddr(address, addrPort.Addr().Unmap(), hostAllowed)
+		},
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// Resolve resolves address for sending datagrams to it and returns the
+// first resolved address the policy allows. Refusals are returned as
+// *EgressError. network is "udp", "udp4" or "udp6".
+func (p *EgressPolicy) Resolve(ctx context.Context, network, address string) (netip.AddrPort, error) {
+	host, port, err := p.checkPort(address)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	ipNetwork := "ip" + strings.TrimPrefix(network, "udp")
+	ips, err := p.LookupHost(ctx, ipNetwork, host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	hostAllowed := p.hostAllowed(host)
+	for _, ip := range ips {
+		if err = p.checkAddr(address, ip.Unmap(), hostAllowed); err == nil {
+			return netip.AddrPortFrom(ip.Unmap(), uint16(port)), nil
+		}
+	}
+	return netip.AddrPort{}, err
+}
+
+// LookupHost resolves host on network, "ip", "ip4" or "ip6", unless the
+// policy refuses to look it up. Refusals are returned as *EgressError. The
+// addresses returned are still checked when connecting to them.
+func (p *EgressPolicy) LookupHost(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	if err := p.checkName(host, host); err != nil {
+		return nil, err
+	}
+	return net.DefaultResolver.LookupNetIP(ctx, network, host)
+}
+
+// checkName refuses a host name that no allowed destination could match: the
+// policy allows hosts but no CIDRs, and host isn't one of them. IP literals
+// are left to checkAddr.
+func (p *EgressPolicy) checkName(address, host string) error {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return nil
+	}
+	if len(p.hosts) > 0 && len(p.cidrs) == 0 && !p.hostAllowed(host) {
+		return &EgressError{Address: address, Reason: "host not allowed"}
+	}
+	return nil
+}
+
+// checkPort splits address and checks its port against the policy.
+func (p *EgressPolicy) checkPort(address string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, &EgressError{Address: address, Reason: "invalid port"}
+	}
+	if p.ports != nil && !p.ports[port] {
+		return "", 0, &EgressError{Address: address, Reason: "port not allowed"}
+	}
+	return host, port, nil
+}
+
+// checkAddr decides on the resolved address of a destination.
+func (p *EgressPolicy) checkAddr(address string, ip netip.Addr, hostAllowed bool) error {
+	for _, prefix := range p.cidrs {
+		if prefix.Contains(ip) {
+			return nil
+		}
+	}
+	if !isPublic(ip) {
+		return &EgressError{Address: address, Reason: fmt.Sprintf("%s is not a public address", ip)}
+	}
+	if hostAllowed || (len(p.hosts) == 0 && len(p.cidrs) == 0) {
+		return nil
+	}
+	return &EgressError{Address: address, Reason: "host not allowed"}
+}
+
+// hostAllowed reports whether host matches one of the allowed names.
+func (p *EgressPolicy) hostAllowed(host string) bool {
+	host = normalizeHost(host)
+	for _, pattern := range p.hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// isPublic reports whether ip is a globally routable unicast address.
+func isPublic(ip netip.Addr) bool {
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package host_functions
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"93.184.216.34", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"ff02::1", false},
+		// Reserved ranges that netip counts as global unicast
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"198.19.255.254", false},
+		{"240.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+		// Just outside them
+		{"100.63.255.255", true},
+		{"100.128.0.1", true},
+		{"198.20.0.1", true},
+	}
+	for _, tt := range tests {
+		if got := isPublic(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("isPublic(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestEgressPolicyCheckAddr(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts []string
+		cidrs []string
+		host  string
+		ip    string
+		want  bool
+	}{
+		{"open policy, public", nil, nil, "example.com", "93.184.216.34", true},
+		{"open policy, loopback", nil, nil, "localhost", "127.0.0.1", false},
+		{"open policy, metadata", nil, nil, "metadata", "169.254.169.254", false},
+		{"allowed host", []string{"api.example.com"}, nil, "api.example.com", "93.184.216.34", true},
+		{"allowed host, case and dot", []string{"API.example.com."}, nil, "api.EXAMPLE.com.", "93.184.216.34", true},
+		{"other host", []string{"api.example.com"}, nil, "example.com", "93.184.216.34", false},
+		{"allowed host resolving inside", []string{"api.example.com"}, nil, "api.example.com", "10.0.0.1", false},
+		{"wildcard subdomain", []string{"*.example.com"}, nil, "a.b.example.com", "93.184.216.34", true},
+		{"wildcard excludes apex", []string{"*.example.com"}, nil, "example.com", "93.184.216.34", false},
+		{"wildcard excludes lookalike", []string{"*.example.com"}, nil, "badexample.com", "93.184.216.34", false},
+		{"allowed CIDR", nil, []string{"10.1.0.0/16"}, "db.internal", "10.1.2.3", true},
+		{"outside CIDR", nil, []string{"10.1.0.0/16"}, "db.internal", "10.2.0.1", false},
+		{"outside CIDR, public", nil, []string{"10.1.0.0/16"}, "example.com", "93.184.216.34", false},
+		{"CIDR opens loopback", nil, []string{"127.0.0.0/8"}, "localhost", "127.0.0.1", true},
+		{"host or CIDR", []string{"api.example.com"}, []string{"10.1.0.0/16"}, "api.example.com", "93.184.216.34", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewEgressPolicy(tt.hosts, tt.cidrs, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			address := net.JoinHostPort(tt.host, "443")
+			err = p.checkAddr(address, netip.MustParseAddr(tt.ip), p.hostAllowed(tt.host))
+			if got := err == nil; got != tt.want {
+				t.Fatalf("checkAddr(%s, %s) = %v, want allowed %v", tt.host, tt.ip, err, tt.want)
+			}
+			var egressErr *EgressError
+			if err != nil && !errors.As(err, &egressErr) {
+				t.Fatalf("checkAddr returned %T, want *EgressError", err)
+			}
+		})
+	}
+}
+
+func TestEgressPolicyCheckName(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts []string
+		cidrs []string
+		host  string
+		want  bool
+	}{
+		{"open policy", nil, nil, "example.com", true},
+		{"hosts only, allowed", []string{"api.example.com"}, nil, "api.example.com", true},
+		{"hosts only, other", []string{"api.example.com"}, nil, "example.com", false},
+		{"hosts only, IP literal", []string{"api.example.com"}, nil, "93.184.216.34", true},
+		{"hosts and CIDRs, other", []string{"api.example.com"}, []string{"10.1.0.0/16"}, "example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewEgressPolicy(tt.hosts, tt.cidrs, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.checkName(tt.host, tt.host) == nil; got != tt.want {
+				t.Fatalf("checkName(%s) allowed %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEgressPolicyCheckPort(t *testing.T) {
+	p, err := NewEgressPolicy(nil, nil, []int{443})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for address, want := range map[string]bool{
+		"example.com:443":   true,
+		"example.com:80":    false,
+		"example.com:99999": false,
+		"example.com:https": false,
+	} {
+		if _, _, err := p.checkPort(address); (err == nil) != want {
+			t.Errorf("checkPort(%s) = %v, want allowed %v", address, err, want)
+		}
+	}
+}
+
+func TestNewEgressPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		hosts []string
+		cidrs []string
+		ports []int
+	}{
+		{"empty host", []string{""}, nil, nil},
+		{"bare wildcard", []string{"*."}, nil, nil},
+		{"inner wildcard", []string{"api.*.example.com"}, nil, nil},
+		{"bad CIDR", nil, []string{"10.0.0.0/33"}, nil},
+		{"address without mask", nil, []string{"10.0.0.1"}, nil},
+		{"port zero", nil, nil, []int{0}},
+		{"port too large", nil, nil, []int{65536}},
+	}
+	for _, tt := range tests {
+		if _, err := NewEgressPolicy(tt.hosts, tt.cidrs, tt.ports); err == nil {
+			t.Errorf("%s: NewEgressPolicy succeeded, want an error", tt.name)
+		}
+	}
+}
+
+func TestEgressPolicyDialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listening on loopback: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	address := ln.Addr().String()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	open, _ := NewEgressPolicy(nil, nil, nil)
+	if _, err := open.DialContext(context.Background(), "tcp", address); !errors.As(err, new(*EgressError)) {
+		t.Fatalf("dialing loopback without a CIDR: got %v, want *EgressError", err)
+	}
+
+	loopback, _ := NewEgressPolicy(nil, []string{"127.0.0.0/8"}, []int{port})
+	conn, err := loopback.DialContext(context.Background(), "tcp", address)
+	if err != nil {
+		t.Fatalf("dialing an allowed CIDR: %v", err)
+	}
+	conn.Close()
+
+	otherPort, _ := NewEgressPolicy(nil, []string{"127.0.0.0/8"}, []int{port + 1})
+	if _, err := otherPort.DialContext(context.Background(), "tcp", address); !errors.As(err, new(*EgressError)) {
+		t.Fatalf("dialing a port not allowed: got %v, want *EgressError", err)
+	}
+
+	hostsOnly, _ := NewEgressPolicy([]string{"api.example.com"}, nil, nil)
+	if _, err := hostsOnly.DialContext(context.Background(), "tcp", net.JoinHostPort("localhost", strconv.Itoa(port))); !errors.As(err, new(*EgressError)) {
+		t.Fatalf("dialing a host not allowed: got %v, want *EgressError", err)
+	}
+}
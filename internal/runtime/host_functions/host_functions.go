@@ -3,21 +3,140 @@
 package host_functions
 
 import (
+	"context"
+	"errors"
+	"log"
+
 	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/google/uuid"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
 )
 
-// Link attaches all host functions to the Wasmtime linker.
-func Link(store *wasmtime.Store, linker *wasmtime.Linker) error {
+// State is the per-session data host functions work on. It is attached to
+// each store with wasmtime.NewStoreWithData and reached through Caller.Data,
+// which keeps the linker itself free of per-tenant state.
+type State struct {
+	DeploymentID uuid.UUID
+	Network      *Network
+	Sockets      *SocketTable
+	Pending      *PendingResponses
+	HTTPStreams  *HTTPStreams
+	Logs         *GuestLogs
+	// KV backs the host_kv functions; nil makes them fail with
+	// ErrCodeUnavailable.
+	KV kv.Store
+	// Secrets backs host_secret_get; nil makes it fail with
+	// ErrCodeUnavailable.
+	Secrets SecretStore
+	// Metrics backs host_metric; nil makes it fail with ErrCodeUnavailable.
+	Metrics MetricSink
+	// Invoker runs the deployments called with host_invoke; nil makes it
+	// fail with ErrCodeUnavailable.
+	Invoker Invoker
+
+	ctx     context.Context
+	secrets sessionSecrets
+}
+
+// NewState creates the host state for a new session of a deployment. A nil
+// network allows public addresses only, with DefaultHTTPLimits.
+func NewState(deploymentID uuid.UUID, network *Network) *State {
+	if network == nil {
+		network = NewNetwork(nil, DefaultHTTPLimits)
+	}
+	return &State{
+		DeploymentID: deploymentID,
+		Network:      network,
+		Sockets:      NewSocketTable(),
+		Pending:      NewPendingResponses(),
+		HTTPStreams:  NewHTTPStreams(),
+		Logs:         NewGuestLogs(DefaultLogLimit),
+		ctx:          context.Background(),
+	}
+}
+
+// SetContext sets the context of the running execution. Host calls made on
+// the guest's behalf are cancelled with it, so they can't outlive the
+// execution's deadline.
+func (s *State) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Context returns the context of the running execution.
+func (s *State) Context() context.Context {
+	return s.ctx
+}
+
+// Close releases everything the session's guests left open and reports
+// their secret reads to the secret store.
+func (s *State) Close() error {
+	s.Pending.Clear()
+	if reads := s.secrets.drain(); len(reads) > 0 && s.Secrets != nil {
+		s.Secrets.AuditSecrets(s.ctx, s.DeploymentID, reads)
+	}
+	return errors.Join(s.HTTPStreams.Close(), s.Sockets.Close())
+}
+
+// egressDenied reports whether err is a refusal by the session's egress
+// policy, logging it against the deployment if so.
+func (s *State) egressDenied(function string, err error) bool {
+	var egressErr *EgressError
+	if !errors.As(err, &egressErr) {
+		return false
+	}
+	log.Printf("%s: deployment %s: %v\n", function, s.DeploymentID, egressErr)
+	return true
+}
+
+// Link attaches all host functions to the Wasmtime linker. Functions are
+// defined independently of any store, so the linker can be shared.
+func Link(linker *wasmtime.Linker) error {
 	// Add legacy WASI preview 1 socket functions that might be expected by some WASM modules
 	if err := DefineLegacyWasiSockets(linker); err != nil {
 		return err
 	}
 
 	// Link socket functions
-	if err := LinkSocketFunctions(store, linker); err != nil {
+	if err := LinkSocketFunctions(linker); err != nil {
 		return err
 	}
 
 	// Link HTTP functions
-	return LinkHTTPFunctions(store, linker)
+	if err := LinkHTTPFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link streaming HTTP functions
+	if err := LinkHTTPStreamFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link key-value store functions
+	if err := LinkKVFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link secret functions
+	if err := LinkSecretFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link structured logging functions
+	if err := LinkLogFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link metric functions
+	if err := LinkMetricFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link deployment invocation functions
+	if err := LinkInvokeFunctions(linker); err != nil {
+		return err
+	}
+
+	// Link the functions fetching responses of two-phase calls
+	return LinkResponseFunctions(linker)
 }
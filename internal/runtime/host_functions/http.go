@@ -4,97 +4,216 @@ package host_functions
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"net"
 	nethttp "net/http"
+	"time"
 
 	"github.com/bytecodealliance/wasmtime-go/v41"
 )
 
-// LinkHTTPFunctions attaches the HTTP host functions to the Wasmtime linker.
-func LinkHTTPFunctions(store *wasmtime.Store, linker *wasmtime.Linker) error {
-	return linker.DefineFunc(store, "env", "host_http_request", func(caller *wasmtime.Caller, reqPtr, reqLen, respPtr, respLen int32) int32 {
-		memory := caller.GetExport("memory").Memory()
-		if memory == nil {
-			log.Println("host_http_request: failed to get memory export")
-			return 0
-		}
+// Error codes returned to guests in HostHTTPResponse.Code, besides
+// EgressDeniedCode.
+const (
+	TimeoutCode          = "timeout"
+	TooManyRedirectsCode = "too_many_redirects"
+	ResponseTooLargeCode = "response_too_large"
+	RequestFailedCode    = "request_failed"
+)
 
-		// Read the request JSON from guest memory.
-		reqBytes := memory.UnsafeData(store)[reqPtr : reqPtr+reqLen]
+// Idle connections kept per deployment for reuse across executions.
+const (
+	maxIdleConns        = 64
+	maxIdleConnsPerHost = 8
+	idleConnTimeout     = 90 * time.Second
+)
 
-		// Define the structures for HTTP requests/responses
-		type HostHTTPRequest struct {
-			Method  string              `json:"method"`
-			URL     string              `json:"url"`
-			Headers map[string][]string `json:"headers"`
-			Body    []byte              `json:"body"`
-		}
+var errTooManyRedirects = errors.New("too many redirects")
+
+// HTTPLimits bounds the outbound HTTP requests of a deployment.
+type HTTPLimits struct {
+	// ConnectTimeout bounds establishing a connection, TLS handshake included.
+	ConnectTimeout time.Duration
+	// Timeout bounds a whole request, from dialing to reading the body. The
+	// execution deadline applies as well.
+	Timeout time.Duration
+	// MaxBodyBytes caps the response body handed back to the guest.
+	MaxBodyBytes int64
+	// MaxRedirects is how many redirects are followed before giving up.
+	MaxRedirects int
+}
 
-		type HostHTTPResponse struct {
-			StatusCode int                 `json:"status_code"`
-			Headers    map[string][]string `json:"headers"`
-			Body       []byte              `json:"body"`
-		}
+// DefaultHTTPLimits apply to deployments created without a Network.
+var DefaultHTTPLimits = HTTPLimits{
+	ConnectTimeout: 5 * time.Second,
+	Timeout:        30 * time.Second,
+	MaxBodyBytes:   10 << 20,
+	MaxRedirects:   10,
+}
 
-		// Unmarshal the request.
-		var hostReq HostHTTPRequest
-		if err := json.Unmarshal(reqBytes, &hostReq); err != nil {
-			log.Printf("host_http_request: failed to unmarshal request JSON: %v\n", err)
-			return 0
-		}
+// Network is the outbound networking of one deployment: its egress policy
+// and an HTTP client whose pooled connections are reused by all of the
+// deployment's executions.
+type Network struct {
+	Egress *EgressPolicy
+	HTTP   *nethttp.Client
+	Limits HTTPLimits
+}
 
-		// Create the request on the host.
-		req, err := nethttp.NewRequest(hostReq.Method, hostReq.URL, bytes.NewReader(hostReq.Body))
-		if err != nil {
-			log.Printf("host_http_request: failed to create request: %v\n", err)
-			return 0
+// NewNetwork builds the networking of a deployment. A nil egress policy
+// allows public addresses only.
+func NewNetwork(egress *EgressPolicy, limits HTTPLimits) *Network {
+	if egress == nil {
+		egress = &EgressPolicy{}
+	}
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		if limits.ConnectTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, limits.ConnectTimeout)
+			defer cancel()
 		}
-		req.Header = nethttp.Header(hostReq.Headers)
+		return egress.DialContext(ctx, network, address)
+	}
+	return &Network{
+		Egress: egress,
+		Limits: limits,
+		HTTP: &nethttp.Client{
+			// Every connection, including those for redirects, goes through
+			// the egress policy, and no proxy is used.
+			Transport: &nethttp.Transport{
+				DialContext:         dial,
+				TLSHandshakeTimeout: limits.ConnectTimeout,
+				MaxIdleConns:        maxIdleConns,
+				MaxIdleConnsPerHost: maxIdleConnsPerHost,
+				IdleConnTimeout:     idleConnTimeout,
+				ForceAttemptHTTP2:   true,
+			},
+			Timeout: limits.Timeout,
+			CheckRedirect: func(_ *nethttp.Request, via []*nethttp.Request) error {
+				if len(via) > limits.MaxRedirects {
+					return fmt.Errorf("%w: stopped after %d", errTooManyRedirects, limits.MaxRedirects)
+				}
+				return nil
+			},
+		},
+	}
+}
 
-		// Make the request using a standard http.Transport to avoid recursion
-		client := &nethttp.Client{
-			Transport: &nethttp.Transport{}, // Use a fresh, unconfigured transport
-		}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("host_http_request: failed to execute request: %v\n", err)
-			return 0
-		}
-		defer resp.Body.Close()
+// Close drops the pooled connections.
+func (n *Network) Close() {
+	n.HTTP.CloseIdleConnections()
+}
 
-		// Read the response body.
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("host_http_request: failed to read response body: %v\n", err)
-			return 0
-		}
+// httpErrorCode maps a failed request to the code reported to the guest.
+func httpErrorCode(err error) string {
+	var egressErr *EgressError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &egressErr):
+		return EgressDeniedCode
+	case errors.Is(err, errTooManyRedirects):
+		return TooManyRedirectsCode
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return TimeoutCode
+	default:
+		return RequestFailedCode
+	}
+}
 
-		// Create the response structure.
-		hostResp := HostHTTPResponse{
-			StatusCode: resp.StatusCode,
-			Headers:    map[string][]string(resp.Header),
-			Body:       body,
-		}
+// HostHTTPRequest is the request a guest passes to the HTTP host functions.
+type HostHTTPRequest struct {
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers"`
+	Body    []byte              `json:"body"`
+}
 
-		// Marshal the response to JSON.
-		respBytes, err := json.Marshal(hostResp)
-		if err != nil {
-			log.Printf("host_http_request: failed to marshal response JSON: %v\n", err)
-			return 0
-		}
+// HostHTTPResponse is the response handed back to the guest.
+type HostHTTPResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers"`
+	Body       []byte              `json:"body"`
+	Error      string              `json:"error,omitempty"`
+	Code       string              `json:"code,omitempty"` // Why the request failed, e.g. EgressDeniedCode
+}
 
-		// Check if the result buffer is large enough.
-		if int32(len(respBytes)) > respLen {
-			log.Printf("host_http_request: result buffer too small. Needed %d, have %d\n", len(respBytes), respLen)
-			return 0
-		}
+// LinkHTTPFunctions attaches the HTTP host functions to the Wasmtime linker:
+// host_http_call_v2 with protobuf messages and host_http_call with JSON, both
+// with the two-phase ABI of wrapCall, and host_http_request with JSON and the
+// legacy ABI.
+func LinkHTTPFunctions(linker *wasmtime.Linker) error {
+	if err := linker.FuncWrap("env", "host_http_call_v2", wrapCall("host_http_call_v2", httpCallProto)); err != nil {
+		return err
+	}
+	if err := linker.FuncWrap("env", "host_http_call", wrapCall("host_http_call", httpCall)); err != nil {
+		return err
+	}
+	return linker.FuncWrap("env", "host_http_request", wrapLegacyCall("host_http_request", httpCall))
+}
+
+// httpCall performs the JSON-encoded HTTP request in reqBytes.
+func httpCall(state *State, reqBytes []byte) ([]byte, error) {
+	var hostReq HostHTTPRequest
+	if err := json.Unmarshal(reqBytes, &hostReq); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	hostResp, err := doHTTP(state, hostReq)
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := json.Marshal(hostResp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response JSON: %w", err)
+	}
+	return respBytes, nil
+}
 
-		// Write the JSON response back into the guest's memory buffer.
-		copy(memory.UnsafeData(store)[respPtr:respPtr+int32(len(respBytes))], respBytes)
+// doHTTP performs hostReq with the deployment's client.
+func doHTTP(state *State, hostReq HostHTTPRequest) (HostHTTPResponse, error) {
+	// Create the request on the host. It is abandoned when the execution's
+	// deadline passes.
+	req, err := nethttp.NewRequestWithContext(state.Context(), hostReq.Method, hostReq.URL, bytes.NewReader(hostReq.Body))
+	if err != nil {
+		return HostHTTPResponse{}, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	req.Header = nethttp.Header(hostReq.Headers)
+
+	// Make the request with the deployment's shared client, so
+	// connections are pooled across executions.
+	network := state.Network
+	var hostResp HostHTTPResponse
+	resp, err := network.HTTP.Do(req)
+	if err != nil {
+		if !state.egressDenied("host_http", err) {
+			log.Printf("host_http: failed to execute request: %v\n", err)
+		}
+		hostResp = HostHTTPResponse{Error: err.Error(), Code: httpErrorCode(err)}
+	} else {
+		defer resp.Body.Close()
 
-		// Return the length of the JSON response written.
-		return int32(len(respBytes))
-	})
+		// Read the response body, one byte past the cap to detect overflow.
+		body, err := io.ReadAll(io.LimitReader(resp.Body, network.Limits.MaxBodyBytes+1))
+		switch {
+		case err != nil:
+			log.Printf("host_http: failed to read response body: %v\n", err)
+			hostResp = HostHTTPResponse{Error: err.Error(), Code: httpErrorCode(err)}
+		case int64(len(body)) > network.Limits.MaxBodyBytes:
+			hostResp = HostHTTPResponse{
+				Error: fmt.Sprintf("response body exceeds %d bytes", network.Limits.MaxBodyBytes),
+				Code:  ResponseTooLargeCode,
+			}
+		default:
+			hostResp = HostHTTPResponse{
+				StatusCode: resp.StatusCode,
+				Headers:    map[string][]string(resp.Header),
+				Body:       body,
+			}
+		}
+	}
+	return hostResp, nil
 }
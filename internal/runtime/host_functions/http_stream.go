@@ -0,0 +1,334 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	nethttp "net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// maxHTTPStreams bounds the HTTP streams a session may hold open.
+const maxHTTPStreams = 16
+
+// httpStream is an outbound HTTP request whose bodies are streamed through
+// the guest chunk by chunk instead of being held in guest memory at once.
+type httpStream struct {
+	body   *io.PipeWriter
+	cancel context.CancelFunc
+	done   chan struct{} // closed once resp or err is set
+
+	resp *nethttp.Response
+	err  error
+
+	// bodyClosed is set once the request body is complete.
+	bodyClosed bool
+}
+
+// start sends req with client in the background. The request body is read
+// from the stream's pipe as the guest writes it.
+func (s *httpStream) start(client *nethttp.Client, req *nethttp.Request) {
+	go func() {
+		defer close(s.done)
+		s.resp, s.err = client.Do(req)
+	}()
+}
+
+// finish completes the request body and waits for the response headers.
+func (s *httpStream) finish() (*nethttp.Response, error) {
+	if !s.bodyClosed {
+		s.body.Close()
+		s.bodyClosed = true
+	}
+	<-s.done
+	return s.resp, s.err
+}
+
+// Close abandons the request and releases its response.
+func (s *httpStream) Close() error {
+	s.cancel()
+	s.body.CloseWithError(context.Canceled)
+	<-s.done
+	if s.resp != nil {
+		return s.resp.Body.Close()
+	}
+	return nil
+}
+
+// streamBody is the body of a streamed request: an optional first chunk
+// from the opening request, then whatever the guest writes.
+type streamBody struct {
+	io.Reader
+	pipe *io.PipeReader
+}
+
+func (b *streamBody) Close() error {
+	return b.pipe.Close()
+}
+
+// HTTPStreams holds the HTTP streams opened by one session. Handles are only
+// meaningful within the session. It is safe for concurrent use.
+type HTTPStreams struct {
+	mu         sync.Mutex
+	streams    map[int32]*httpStream
+	nextHandle int32
+}
+
+// NewHTTPStreams creates an empty table. Handles start at 1.
+func NewHTTPStreams() *HTTPStreams {
+	return &HTTPStreams{
+		streams:    make(map[int32]*httpStream),
+		nextHandle: 1,
+	}
+}
+
+// Add registers stream and returns its handle, or false if too many are open.
+func (t *HTTPStreams) Add(stream *httpStream) (int32, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.streams) >= maxHTTPStreams {
+		return 0, false
+	}
+	handle := t.nextHandle
+	t.nextHandle++
+	t.streams[handle] = stream
+	return handle, true
+}
+
+// Get returns the stream registered under handle.
+func (t *HTTPStreams) Get(handle int32) (*httpStream, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stream, ok := t.streams[handle]
+	return stream, ok
+}
+
+// Remove unregisters and returns the stream under handle.
+func (t *HTTPStreams) Remove(handle int32) (*httpStream, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stream, ok := t.streams[handle]
+	delete(t.streams, handle)
+	return stream, ok
+}
+
+// Close closes every stream still open and empties the table.
+func (t *HTTPStreams) Close() error {
+	t.mu.Lock()
+	streams := t.streams
+	t.streams = make(map[int32]*httpStream)
+	t.mu.Unlock()
+
+	var errs []error
+	for _, stream := range streams {
+		if err := stream.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// openHTTPStream starts the protobuf-encoded types.HostHTTPRequest in
+// reqBytes with the deployment's client, so the egress policy and HTTP limits
+// apply as for any other request.
+func openHTTPStream(state *State, reqBytes []byte) (*httpStream, error) {
+	var hostReq types.HostHTTPRequest
+	if err := proto.Unmarshal(reqBytes, &hostReq); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+
+	// The stream may outlive the host call that opened it, but not the
+	// execution.
+	ctx, cancel := context.WithCancel(state.Context())
+	pr, pw := io.Pipe()
+	body := &streamBody{Reader: io.MultiReader(bytes.NewReader(hostReq.Body), pr), pipe: pr}
+	req, err := nethttp.NewRequestWithContext(ctx, hostReq.Method, hostReq.Url, body)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	req.Header = nethttp.Header(headersFromProto(hostReq.Headers))
+	// A declared length is sent as is; otherwise the body is chunked.
+	req.ContentLength = -1
+	if length := req.Header.Get("Content-Length"); length != "" {
+		if req.ContentLength, err = strconv.ParseInt(length, 10, 64); err != nil {
+			cancel()
+			return nil, fmt.Errorf("%w: invalid Content-Length %q", errInvalidRequest, length)
+		}
+	}
+
+	stream := &httpStream{body: pw, cancel: cancel, done: make(chan struct{})}
+	stream.start(state.Network.HTTP, req)
+	return stream, nil
+}
+
+// LinkHTTPStreamFunctions attaches the streaming HTTP host functions to the
+// Wasmtime linker:
+//
+//	host_http_open(reqPtr, reqLen i32) -> i32
+//	host_http_write(handle, bufPtr, bufLen i32) -> i32
+//	host_http_response(handle, respPtr, respLen, handlePtr i32) -> i32
+//	host_http_read(handle, bufPtr, bufLen i32) -> i32
+//	host_http_close(handle i32) -> i32
+//
+// host_http_open starts the request described by a protobuf
+// types.HostHTTPRequest and returns a stream handle; its body, if any, is
+// sent first. host_http_write sends the next chunk of the request body and
+// returns its length. host_http_response ends the request body, waits for
+// the response and hands back a types.HostHTTPResponse without a body, like
+// wrapCall does. host_http_read reads the next chunk of the response body
+// into the buffer and returns its length, or 0 once the body is complete.
+// host_http_close releases the stream, and must be called even after a
+// failure. All return a negative status code on failure; when a write fails,
+// host_http_response tells why.
+func LinkHTTPStreamFunctions(linker *wasmtime.Linker) error {
+	err := linker.FuncWrap("env", "host_http_open", func(caller *wasmtime.Caller, reqPtr, reqLen int32) int32 {
+		reqBytes, ok := guestSlice(guestMemory(caller), reqPtr, reqLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_http_open: store has no host state")
+			return ErrCodeNoState
+		}
+		stream, err := openHTTPStream(state, append([]byte(nil), reqBytes...))
+		if err != nil {
+			log.Printf("host_http_open: %v\n", err)
+			return callErrorCode(err)
+		}
+		handle, ok := state.HTTPStreams.Add(stream)
+		if !ok {
+			stream.Close()
+			return ErrCodeTooManyStreams
+		}
+		return handle
+	})
+	if err != nil {
+		return err
+	}
+
+	err = linker.FuncWrap("env", "host_http_write", func(caller *wasmtime.Caller, handle, bufPtr, bufLen int32) int32 {
+		buf, ok := guestSlice(guestMemory(caller), bufPtr, bufLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_http_write: store has no host state")
+			return ErrCodeNoState
+		}
+		stream, ok := state.HTTPStreams.Get(handle)
+		if !ok {
+			return ErrCodeBadHandle
+		}
+		if stream.bodyClosed {
+			return ErrCodeBadState
+		}
+		// The guest is blocked in this call, so its memory stays put while
+		// the transport reads from it.
+		n, err := stream.body.Write(buf)
+		if err != nil {
+			return ErrCodeIO
+		}
+		return int32(n)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = linker.FuncWrap("env", "host_http_response", func(caller *wasmtime.Caller, handle, respPtr, respLen, handlePtr int32) int32 {
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_http_response: store has no host state")
+			return ErrCodeNoState
+		}
+		stream, ok := state.HTTPStreams.Get(handle)
+		if !ok {
+			return ErrCodeBadHandle
+		}
+		var hostResp types.HostHTTPResponse
+		resp, err := stream.finish()
+		if err != nil {
+			if !state.egressDenied("host_http", err) {
+				log.Printf("host_http: failed to execute request: %v\n", err)
+			}
+			hostResp = types.HostHTTPResponse{Error: err.Error(), Code: httpErrorCode(err)}
+		} else {
+			hostResp = types.HostHTTPResponse{
+				StatusCode: int32(resp.StatusCode),
+				Headers:    headersToProto(resp.Header),
+			}
+		}
+		respBytes, err := proto.Marshal(&hostResp)
+		if err != nil {
+			log.Printf("host_http_response: failed to marshal response: %v\n", err)
+			return ErrCodeInternal
+		}
+		return deliver(caller, state, respBytes, respPtr, respLen, handlePtr)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = linker.FuncWrap("env", "host_http_read", func(caller *wasmtime.Caller, handle, bufPtr, bufLen int32) int32 {
+		buf, ok := guestSlice(guestMemory(caller), bufPtr, bufLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_http_read: store has no host state")
+			return ErrCodeNoState
+		}
+		stream, ok := state.HTTPStreams.Get(handle)
+		if !ok {
+			return ErrCodeBadHandle
+		}
+		// The response must have been received with host_http_response.
+		if !stream.bodyClosed {
+			return ErrCodeBadState
+		}
+		resp, err := stream.finish()
+		if err != nil {
+			return ErrCodeBadState
+		}
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 || errors.Is(err, io.EOF) || len(buf) == 0 {
+				return int32(n)
+			}
+			if err != nil {
+				return ErrCodeIO
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return linker.FuncWrap("env", "host_http_close", func(caller *wasmtime.Caller, handle int32) int32 {
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_http_close: store has no host state")
+			return ErrCodeNoState
+		}
+		stream, ok := state.HTTPStreams.Remove(handle)
+		if !ok {
+			return ErrCodeBadHandle
+		}
+		stream.Close()
+		return 0
+	})
+}
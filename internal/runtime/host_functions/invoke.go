@@ -0,0 +1,155 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	nethttp "net/http"
+	"slices"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// Error codes returned to guests in HostInvokeResponse.Code, besides
+// TimeoutCode.
+const (
+	DeploymentNotFoundCode = "not_found"
+	CallDepthExceededCode  = "depth_exceeded"
+	CallCycleCode          = "cycle"
+	InvokeFailedCode       = "invoke_failed"
+)
+
+// CallerHeader is set by the host on requests made with host_invoke to the
+// calling deployment's ID, replacing any value the guest gave.
+const CallerHeader = "X-Ignis-Caller"
+
+var (
+	// ErrInvokeNotFound means the target names no deployment.
+	ErrInvokeNotFound = errors.New("deployment not found")
+	// ErrInvokeDepthExceeded means the call would nest deeper than allowed.
+	ErrInvokeDepthExceeded = errors.New("call depth exceeded")
+	// ErrInvokeCycle means the target is already in the call chain.
+	ErrInvokeCycle = errors.New("call cycle")
+	// ErrInvokeTimeout means the target ran out of time, which includes the
+	// caller's deadline.
+	ErrInvokeTimeout = errors.New("call timed out")
+	// ErrInvokeUnavailable means the session can't invoke deployments.
+	ErrInvokeUnavailable = errors.New("invoke unavailable")
+)
+
+// Invoker runs the deployments guests call with host_invoke. Invoke is given
+// the caller's execution context, carrying its deadline and the CallChain
+// leading to the target, and reports the failures above by wrapping their
+// errors. Implementations must be safe for concurrent use.
+type Invoker interface {
+	Invoke(ctx context.Context, target string, request *types.FDRequest) (*types.FDResponse, error)
+}
+
+type callChainKey struct{}
+
+// CallChain returns the deployments whose host_invoke calls led to the
+// execution of ctx, outermost first, or nil for a request from outside.
+func CallChain(ctx context.Context) []uuid.UUID {
+	chain, _ := ctx.Value(callChainKey{}).([]uuid.UUID)
+	return chain
+}
+
+// withCaller extends the call chain of ctx with caller.
+func withCaller(ctx context.Context, caller uuid.UUID) context.Context {
+	chain := CallChain(ctx)
+	return context.WithValue(ctx, callChainKey{}, append(chain[:len(chain):len(chain)], caller))
+}
+
+// CheckCallChain refuses a call to target from the execution of ctx if it
+// would nest more than maxDepth calls deep, with ErrInvokeDepthExceeded, or
+// if target is already in the call chain, with ErrInvokeCycle.
+func CheckCallChain(ctx context.Context, target uuid.UUID, maxDepth int) error {
+	chain := CallChain(ctx)
+	if len(chain) > maxDepth {
+		return fmt.Errorf("%w: more than %d nested calls", ErrInvokeDepthExceeded, maxDepth)
+	}
+	if slices.Contains(chain, target) {
+		return fmt.Errorf("%w: %s is already running in this call chain", ErrInvokeCycle, target)
+	}
+	return nil
+}
+
+// invokeError maps a failed invocation to the code and message of its
+// HostInvokeResponse. Messages are fixed, so details of the target or the
+// host never reach the caller.
+func invokeError(err error) (code, message string) {
+	switch {
+	case errors.Is(err, ErrInvokeNotFound):
+		return DeploymentNotFoundCode, ErrInvokeNotFound.Error()
+	case errors.Is(err, ErrInvokeDepthExceeded):
+		return CallDepthExceededCode, ErrInvokeDepthExceeded.Error()
+	case errors.Is(err, ErrInvokeCycle):
+		return CallCycleCode, ErrInvokeCycle.Error()
+	case errors.Is(err, ErrInvokeTimeout), errors.Is(err, context.DeadlineExceeded):
+		return TimeoutCode, ErrInvokeTimeout.Error()
+	default:
+		return InvokeFailedCode, "invoke failed"
+	}
+}
+
+func invoke(state *State, req []byte) ([]byte, error) {
+	var hostReq types.HostInvokeRequest
+	if err := proto.Unmarshal(req, &hostReq); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	if hostReq.Target == "" {
+		return nil, fmt.Errorf("%w: empty target", errInvalidRequest)
+	}
+	if state.Invoker == nil {
+		return nil, ErrInvokeUnavailable
+	}
+
+	request := hostReq.Request
+	if request == nil {
+		request = &types.FDRequest{}
+	}
+	if request.Method == "" {
+		request.Method = nethttp.MethodGet
+	}
+	for name := range request.Header {
+		if nethttp.CanonicalHeaderKey(name) == CallerHeader {
+			delete(request.Header, name)
+		}
+	}
+	if request.Header == nil {
+		request.Header = make(map[string]*types.HeaderFields)
+	}
+	request.Header[CallerHeader] = &types.HeaderFields{Fields: []string{state.DeploymentID.String()}}
+
+	var hostResp types.HostInvokeResponse
+	resp, err := state.Invoker.Invoke(withCaller(state.Context(), state.DeploymentID), hostReq.Target, request)
+	if err != nil {
+		log.Printf("host_invoke: deployment %s calling %q: %v\n", state.DeploymentID, hostReq.Target, err)
+		hostResp.Code, hostResp.Error = invokeError(err)
+	} else {
+		hostResp.Response = resp
+	}
+	return proto.Marshal(&hostResp)
+}
+
+// LinkInvokeFunctions attaches the deployment invocation host function to the
+// Wasmtime linker:
+//
+//	host_invoke(reqPtr, reqLen, respPtr, respLen, handlePtr i32) -> i32
+//
+// It takes a protobuf types.HostInvokeRequest whose target is a deployment
+// UUID, runs the deployment in-process with the request, and hands back a
+// types.HostInvokeResponse like wrapCall does. Deployments have no aliases,
+// so names are refused as not found. Failures of the invocation are reported
+// in the response's code, with a fixed error message, and logged in full on
+// the host; it returns ErrCodeUnavailable when the session has no Invoker.
+func LinkInvokeFunctions(linker *wasmtime.Linker) error {
+	return linker.FuncWrap("env", "host_invoke", wrapCall("host_invoke", invoke))
+}
@@ -0,0 +1,145 @@
+package host_functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+func TestCheckCallChain(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	ab := withCaller(withCaller(context.Background(), a), b)
+	abc := withCaller(ab, c)
+	abd := withCaller(ab, d)
+	if got := CallChain(abc); !slices.Equal(got, []uuid.UUID{a, b, c}) {
+		t.Fatalf("CallChain = %v, want [a b c]", got)
+	}
+	// Branches of the same chain don't share their callers
+	if got := CallChain(abd); !slices.Equal(got, []uuid.UUID{a, b, d}) {
+		t.Fatalf("CallChain of a sibling = %v, want [a b d]", got)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		target   uuid.UUID
+		maxDepth int
+		want     error
+	}{
+		{"outside request", context.Background(), a, 0, nil},
+		{"within depth", abc, d, 3, nil},
+		{"too deep", abc, d, 2, ErrInvokeDepthExceeded},
+		{"calling itself", abc, c, 4, ErrInvokeCycle},
+		{"calling the origin", abc, a, 4, ErrInvokeCycle},
+		{"cycle on another branch", abd, c, 4, nil},
+	}
+	for _, tt := range tests {
+		err := CheckCallChain(tt.ctx, tt.target, tt.maxDepth)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: CheckCallChain = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+type fakeInvoker struct {
+	ctx     context.Context
+	request *types.FDRequest
+	err     error
+}
+
+func (f *fakeInvoker) Invoke(ctx context.Context, target string, request *types.FDRequest) (*types.FDResponse, error) {
+	f.ctx, f.request = ctx, request
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &types.FDResponse{StatusCode: 200}, nil
+}
+
+func callInvoke(t *testing.T, state *State, req *types.HostInvokeRequest) *types.HostInvokeResponse {
+	t.Helper()
+	encodedResp, err := invoke(state, mustMarshal(t, req))
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	var resp types.HostInvokeResponse
+	if err := proto.Unmarshal(encodedResp, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return &resp
+}
+
+func TestInvokeCallerAndChain(t *testing.T) {
+	origin := uuid.New()
+	state := NewState(uuid.New(), nil)
+	state.SetContext(withCaller(context.Background(), origin))
+	invoker := &fakeInvoker{}
+	state.Invoker = invoker
+
+	resp := callInvoke(t, state, &types.HostInvokeRequest{
+		Target: uuid.NewString(),
+		Request: &types.FDRequest{Header: map[string]*types.HeaderFields{
+			"x-ignis-caller": {Fields: []string{origin.String()}},
+		}},
+	})
+	if resp.Code != "" || resp.Response.GetStatusCode() != 200 {
+		t.Fatalf("response = %v", resp)
+	}
+	if got := CallChain(invoker.ctx); !slices.Equal(got, []uuid.UUID{origin, state.DeploymentID}) {
+		t.Errorf("CallChain = %v, want the origin and the caller", got)
+	}
+	if invoker.request.Method != "GET" {
+		t.Errorf("method = %q, want GET", invoker.request.Method)
+	}
+	header := invoker.request.Header
+	if len(header) != 1 || !slices.Equal(header[CallerHeader].GetFields(), []string{state.DeploymentID.String()}) {
+		t.Errorf("header = %v, want only %s set to the caller", header, CallerHeader)
+	}
+}
+
+func TestInvokeErrors(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    string
+		message string
+	}{
+		{fmt.Errorf("%w: x", ErrInvokeNotFound), DeploymentNotFoundCode, ErrInvokeNotFound.Error()},
+		{fmt.Errorf("%w: more than 4 nested calls", ErrInvokeDepthExceeded), CallDepthExceededCode, ErrInvokeDepthExceeded.Error()},
+		{fmt.Errorf("%w: x is already running", ErrInvokeCycle), CallCycleCode, ErrInvokeCycle.Error()},
+		{fmt.Errorf("%w: guest trapped", ErrInvokeTimeout), TimeoutCode, ErrInvokeTimeout.Error()},
+		{context.DeadlineExceeded, TimeoutCode, ErrInvokeTimeout.Error()},
+		{errors.New("dial tcp 10.0.0.5:5432: connection refused"), InvokeFailedCode, "invoke failed"},
+	}
+	for _, tt := range tests {
+		state := NewState(uuid.New(), nil)
+		state.Invoker = &fakeInvoker{err: tt.err}
+		resp := callInvoke(t, state, &types.HostInvokeRequest{Target: uuid.NewString()})
+		if resp.Code != tt.code || resp.Error != tt.message || resp.Response != nil {
+			t.Errorf("%v: response = %v, want code %q and message %q", tt.err, resp, tt.code, tt.message)
+		}
+	}
+
+	state := NewState(uuid.New(), nil)
+	if _, err := invoke(state, mustMarshal(t, &types.HostInvokeRequest{Target: "x"})); !errors.Is(err, ErrInvokeUnavailable) {
+		t.Errorf("invoke without an Invoker = %v, want ErrInvokeUnavailable", err)
+	}
+	state.Invoker = &fakeInvoker{}
+	if _, err := invoke(state, mustMarshal(t, &types.HostInvokeRequest{})); !errors.Is(err, errInvalidRequest) {
+		t.Errorf("invoke without a target = %v, want errInvalidRequest", err)
+	}
+}
+
+func mustMarshal(t *testing.T, m proto.Message) []byte {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
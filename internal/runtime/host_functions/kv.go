@@ -0,0 +1,208 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// maxKVKeyBytes bounds the length of a key.
+const maxKVKeyBytes = 512
+
+// kvKey validates a key or prefix read from the guest. Keys are returned as
+// protobuf strings by host_kv_list, so they must be valid UTF-8.
+func kvKey(b []byte, allowEmpty bool) (string, error) {
+	switch {
+	case len(b) == 0 && !allowEmpty:
+		return "", fmt.Errorf("%w: empty key", errInvalidRequest)
+	case len(b) > maxKVKeyBytes:
+		return "", fmt.Errorf("%w: key longer than %d bytes", errInvalidRequest, maxKVKeyBytes)
+	case !utf8.Valid(b):
+		return "", fmt.Errorf("%w: key is not valid UTF-8", errInvalidRequest)
+	}
+	return string(b), nil
+}
+
+// kvStore returns the session's store, or kv.ErrUnavailable without one.
+func (s *State) kvStore() (kv.Store, error) {
+	if s.KV == nil {
+		return nil, kv.ErrUnavailable
+	}
+	return s.KV, nil
+}
+
+// kvErrorCode maps a failed store operation to its status code, logging
+// failures of the store itself.
+func kvErrorCode(name string, err error) int32 {
+	code := callErrorCode(err)
+	if code == ErrCodeInternal || code == ErrCodeUnavailable {
+		log.Printf("%s: %v\n", name, err)
+	}
+	return code
+}
+
+func kvGet(state *State, req []byte) ([]byte, error) {
+	key, err := kvKey(req, false)
+	if err != nil {
+		return nil, err
+	}
+	kv, err := state.kvStore()
+	if err != nil {
+		return nil, err
+	}
+	return kv.Get(state.Context(), state.DeploymentID.String(), key)
+}
+
+func kvList(state *State, req []byte) ([]byte, error) {
+	prefix, err := kvKey(req, true)
+	if err != nil {
+		return nil, err
+	}
+	kv, err := state.kvStore()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := kv.List(state.Context(), state.DeploymentID.String(), prefix)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&types.HostKVKeys{Keys: keys})
+}
+
+// LinkKVFunctions attaches the key-value host functions to the Wasmtime
+// linker:
+//
+//	host_kv_get(keyPtr, keyLen, respPtr, respLen, handlePtr i32) -> i32
+//	host_kv_set(keyPtr, keyLen, valPtr, valLen i32, ttlMs i64) -> i32
+//	host_kv_delete(keyPtr, keyLen i32) -> i32
+//	host_kv_incr(keyPtr, keyLen i32, delta i64, resultPtr i32) -> i32
+//	host_kv_list(prefixPtr, prefixLen, respPtr, respLen, handlePtr i32) -> i32
+//
+// host_kv_get hands back the value like wrapCall does, or returns
+// ErrCodeNotFound. host_kv_set stores a value, expiring after ttlMs unless
+// it is 0, and returns 0. host_kv_delete returns 1 if the key existed and 0
+// otherwise. host_kv_incr adds delta to an integer value, writes the result
+// as a little-endian i64 at resultPtr and returns 0. host_kv_list hands back
+// a protobuf types.HostKVKeys with the keys starting with the prefix. All
+// return a negative status code on failure, ErrCodeQuotaExceeded when a
+// write would exceed the deployment's quotas.
+func LinkKVFunctions(linker *wasmtime.Linker) error {
+	err := linker.FuncWrap("env", "host_kv_get", wrapCall("host_kv_get", kvGet))
+	if err != nil {
+		return err
+	}
+
+	err = linker.FuncWrap("env", "host_kv_set", func(caller *wasmtime.Caller, keyPtr, keyLen, valPtr, valLen int32, ttlMs int64) int32 {
+		data := guestMemory(caller)
+		keyBytes, ok := guestSlice(data, keyPtr, keyLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		value, ok := guestSlice(data, valPtr, valLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_kv_set: store has no host state")
+			return ErrCodeNoState
+		}
+		key, err := kvKey(keyBytes, false)
+		if err != nil || ttlMs < 0 {
+			return ErrCodeInvalidRequest
+		}
+		kv, err := state.kvStore()
+		if err != nil {
+			return kvErrorCode("host_kv_set", err)
+		}
+		// The value is copied, since the store may hold on to it.
+		ttl := time.Duration(ttlMs) * time.Millisecond
+		if err := kv.Set(state.Context(), state.DeploymentID.String(), key, append([]byte(nil), value...), ttl); err != nil {
+			return kvErrorCode("host_kv_set", err)
+		}
+		return 0
+	})
+	if err != nil {
+		return err
+	}
+
+	err = linker.FuncWrap("env", "host_kv_delete", func(caller *wasmtime.Caller, keyPtr, keyLen int32) int32 {
+		keyBytes, ok := guestSlice(guestMemory(caller), keyPtr, keyLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_kv_delete: store has no host state")
+			return ErrCodeNoState
+		}
+		key, err := kvKey(keyBytes, false)
+		if err != nil {
+			return ErrCodeInvalidRequest
+		}
+		kv, err := state.kvStore()
+		if err != nil {
+			return kvErrorCode("host_kv_delete", err)
+		}
+		existed, err := kv.Delete(state.Context(), state.DeploymentID.String(), key)
+		if err != nil {
+			return kvErrorCode("host_kv_delete", err)
+		}
+		if existed {
+			return 1
+		}
+		return 0
+	})
+	if err != nil {
+		return err
+	}
+
+	err = linker.FuncWrap("env", "host_kv_incr", func(caller *wasmtime.Caller, keyPtr, keyLen int32, delta int64, resultPtr int32) int32 {
+		data := guestMemory(caller)
+		keyBytes, ok := guestSlice(data, keyPtr, keyLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		if _, ok := guestSlice(data, resultPtr, 8); !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_kv_incr: store has no host state")
+			return ErrCodeNoState
+		}
+		key, err := kvKey(keyBytes, false)
+		if err != nil {
+			return ErrCodeInvalidRequest
+		}
+		kv, err := state.kvStore()
+		if err != nil {
+			return kvErrorCode("host_kv_incr", err)
+		}
+		result, err := kv.Incr(state.Context(), state.DeploymentID.String(), key, delta)
+		if err != nil {
+			return kvErrorCode("host_kv_incr", err)
+		}
+		resultBuf, ok := guestSlice(guestMemory(caller), resultPtr, 8)
+		if !ok {
+			return ErrCodeMemory
+		}
+		binary.LittleEndian.PutUint64(resultBuf, uint64(result))
+		return 0
+	})
+	if err != nil {
+		return err
+	}
+
+	return linker.FuncWrap("env", "host_kv_list", wrapCall("host_kv_list", kvList))
+}
@@ -0,0 +1,205 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/logs"
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+const (
+	// maxLogRecordBytes bounds the encoded size of a record.
+	maxLogRecordBytes = 8 << 10
+	// maxLogFields bounds the fields of a record.
+	maxLogFields = 32
+	// maxRetainedLogRecords bounds the records kept from one session for the
+	// log store; later ones still reach the server log.
+	maxRetainedLogRecords = 1000
+)
+
+// LogLimit bounds the records a session may emit with host_log: Burst at
+// once, then PerSecond on average. Of those, records of up to MaxBytes in
+// all are kept for the log store; zero keeps none.
+type LogLimit struct {
+	PerSecond float64
+	Burst     int
+	MaxBytes  int
+}
+
+// DefaultLogLimit applies when a session sets no limit.
+var DefaultLogLimit = LogLimit{PerSecond: 50, Burst: 100, MaxBytes: 64 << 10}
+
+// LogField is a key/value pair attached to a LogRecord.
+type LogField struct {
+	Key   string
+	Value string
+}
+
+// LogRecord is a structured record a guest emitted with host_log.
+type LogRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Fields  []LogField
+}
+
+// GuestLogs rate limits and keeps the records emitted by one session. It is
+// safe for concurrent use.
+type GuestLogs struct {
+	mu      sync.Mutex
+	limit   LogLimit
+	tokens  float64
+	last    time.Time
+	records []LogRecord
+	// size is the size of records, as counted by LogRecord.size.
+	size    int
+	dropped int
+}
+
+// NewGuestLogs creates an empty record list whose rate limit starts with a
+// full burst.
+func NewGuestLogs(limit LogLimit) *GuestLogs {
+	return &GuestLogs{limit: limit, tokens: float64(limit.Burst)}
+}
+
+// add takes a token for record and keeps it, reporting false if the rate
+// limit dropped it.
+func (l *GuestLogs) add(record LogRecord) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.last.IsZero() {
+		l.tokens += record.Time.Sub(l.last).Seconds() * l.limit.PerSecond
+		l.tokens = min(l.tokens, float64(l.limit.Burst))
+	}
+	l.last = record.Time
+	if l.tokens < 1 {
+		l.dropped++
+		return false
+	}
+	l.tokens--
+	if size := record.size(); len(l.records) < maxRetainedLogRecords && l.size+size <= l.limit.MaxBytes {
+		l.records = append(l.records, record)
+		l.size += size
+	} else {
+		l.dropped++
+	}
+	return true
+}
+
+// Drain returns the records kept so far and how many were dropped since the
+// last call, then starts over. The rate limit carries on.
+func (l *GuestLogs) Drain() ([]LogRecord, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	records, dropped := l.records, l.dropped
+	l.records, l.size, l.dropped = nil, 0, 0
+	return records, dropped
+}
+
+// size returns the bytes of text the record holds.
+func (r LogRecord) size() int {
+	n := len(r.Message)
+	for _, field := range r.Fields {
+		n += len(field.Key) + len(field.Value)
+	}
+	return n
+}
+
+// logLevel parses a level name; an empty one means info.
+func logLevel(name string) (slog.Level, bool) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "", "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	}
+	return 0, false
+}
+
+// parseLogRecord decodes and checks a protobuf types.HostLogRecord.
+func parseLogRecord(b []byte) (LogRecord, error) {
+	if len(b) > maxLogRecordBytes {
+		return LogRecord{}, fmt.Errorf("%w: record larger than %d bytes", errInvalidRequest, maxLogRecordBytes)
+	}
+	var hostRecord types.HostLogRecord
+	if err := proto.Unmarshal(b, &hostRecord); err != nil {
+		return LogRecord{}, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	level, ok := logLevel(hostRecord.Level)
+	if !ok {
+		return LogRecord{}, fmt.Errorf("%w: unknown level %q", errInvalidRequest, hostRecord.Level)
+	}
+	if len(hostRecord.Fields) > maxLogFields {
+		return LogRecord{}, fmt.Errorf("%w: more than %d fields", errInvalidRequest, maxLogFields)
+	}
+	record := LogRecord{
+		Time:    time.Now(),
+		Level:   level,
+		Message: hostRecord.Message,
+		Fields:  make([]LogField, len(hostRecord.Fields)),
+	}
+	for i, field := range hostRecord.Fields {
+		if field.Key == "" {
+			return LogRecord{}, fmt.Errorf("%w: field with an empty key", errInvalidRequest)
+		}
+		record.Fields[i] = LogField{Key: field.Key, Value: field.Value}
+	}
+	return record, nil
+}
+
+// LinkLogFunctions attaches the structured logging host function to the
+// Wasmtime linker:
+//
+//	host_log(recPtr, recLen i32) -> i32
+//
+// It takes a protobuf types.HostLogRecord, whose level is debug, info (the
+// default), warn or error. The record is written to the server log with the
+// deployment ID, request ID and time, and its fields grouped under "fields",
+// and kept for the deployment's log store. It returns 0, or 1 when the
+// session's rate limit dropped the record, or a negative status code.
+func LinkLogFunctions(linker *wasmtime.Linker) error {
+	return linker.FuncWrap("env", "host_log", func(caller *wasmtime.Caller, recPtr, recLen int32) int32 {
+		recBytes, ok := guestSlice(guestMemory(caller), recPtr, recLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_log: store has no host state")
+			return ErrCodeNoState
+		}
+		record, err := parseLogRecord(recBytes)
+		if err != nil {
+			return ErrCodeInvalidRequest
+		}
+		if !state.Logs.add(record) {
+			return 1
+		}
+
+		ctx := state.Context()
+		fields := make([]any, len(record.Fields))
+		for i, field := range record.Fields {
+			fields[i] = slog.String(field.Key, field.Value)
+		}
+		slog.Log(ctx, record.Level, record.Message,
+			"deployment_id", state.DeploymentID,
+			"request_id", logs.RequestID(ctx),
+			slog.Group("fields", fields...),
+		)
+		return 0
+	})
+}
@@ -0,0 +1,101 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// maxMetricBytes bounds the encoded size of a sample.
+const maxMetricBytes = 4 << 10
+
+// MetricSink aggregates the samples guests record with host_metric, such as
+// a *metrics.Partitioned keyed by deployment ID. Record fails with an error
+// wrapping metrics.ErrInvalidSample, or with metrics.ErrTooManySeries when
+// the deployment has used up its series. Implementations must be safe for
+// concurrent use.
+type MetricSink interface {
+	Record(partition string, sample metrics.Sample) error
+}
+
+var metricsRejected = metrics.NewCounterVec(
+	"ignis_host_metric_rejected_total",
+	"Samples recorded with host_metric that were rejected, by reason.",
+	"deployment_id", "reason",
+)
+
+// parseMetric decodes a protobuf types.HostMetric.
+func parseMetric(b []byte) (metrics.Sample, error) {
+	if len(b) > maxMetricBytes {
+		return metrics.Sample{}, fmt.Errorf("%w: sample larger than %d bytes", errInvalidRequest, maxMetricBytes)
+	}
+	var hostMetric types.HostMetric
+	if err := proto.Unmarshal(b, &hostMetric); err != nil {
+		return metrics.Sample{}, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	sample := metrics.Sample{
+		Kind:  hostMetric.Kind,
+		Name:  hostMetric.Name,
+		Value: hostMetric.Value,
+		Delta: hostMetric.Delta,
+	}
+	if len(hostMetric.Labels) > 0 {
+		sample.Labels = make(map[string]string, len(hostMetric.Labels))
+		for _, label := range hostMetric.Labels {
+			if _, ok := sample.Labels[label.Key]; ok {
+				return metrics.Sample{}, fmt.Errorf("%w: duplicate label %q", errInvalidRequest, label.Key)
+			}
+			sample.Labels[label.Key] = label.Value
+		}
+	}
+	return sample, nil
+}
+
+// LinkMetricFunctions attaches the metric host function to the Wasmtime
+// linker:
+//
+//	host_metric(samplePtr, sampleLen i32) -> i32
+//
+// It takes a protobuf types.HostMetric whose kind is counter, gauge or
+// histogram, and records it for the deployment. It returns 0, or
+// ErrCodeInvalidRequest for a malformed sample, ErrCodeQuotaExceeded when it would start a series past the
+// deployment's limit, and ErrCodeUnavailable when the session has no sink.
+func LinkMetricFunctions(linker *wasmtime.Linker) error {
+	return linker.FuncWrap("env", "host_metric", func(caller *wasmtime.Caller, samplePtr, sampleLen int32) int32 {
+		sampleBytes, ok := guestSlice(guestMemory(caller), samplePtr, sampleLen)
+		if !ok {
+			return ErrCodeMemory
+		}
+		state, ok := caller.Data().(*State)
+		if !ok {
+			log.Println("host_metric: store has no host state")
+			return ErrCodeNoState
+		}
+		if state.Metrics == nil {
+			return ErrCodeUnavailable
+		}
+		deploymentID := state.DeploymentID.String()
+		sample, err := parseMetric(sampleBytes)
+		if err == nil {
+			err = state.Metrics.Record(deploymentID, sample)
+		}
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, metrics.ErrTooManySeries):
+			metricsRejected.Inc(deploymentID, "quota")
+			return ErrCodeQuotaExceeded
+		default:
+			metricsRejected.Inc(deploymentID, "invalid")
+			return ErrCodeInvalidRequest
+		}
+	})
+}
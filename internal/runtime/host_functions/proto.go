@@ -0,0 +1,88 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+)
+
+// httpCallProto performs the protobuf-encoded types.HostHTTPRequest in
+// reqBytes. Bodies are carried as raw bytes, without the base64 of JSON.
+func httpCallProto(state *State, reqBytes []byte) ([]byte, error) {
+	var req types.HostHTTPRequest
+	if err := proto.Unmarshal(reqBytes, &req); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	hostResp, err := doHTTP(state, HostHTTPRequest{
+		Method:  req.Method,
+		URL:     req.Url,
+		Headers: headersFromProto(req.Headers),
+		Body:    req.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	respBytes, err := proto.Marshal(&types.HostHTTPResponse{
+		StatusCode: int32(hostResp.StatusCode),
+		Headers:    headersToProto(hostResp.Headers),
+		Body:       hostResp.Body,
+		Error:      hostResp.Error,
+		Code:       hostResp.Code,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return respBytes, nil
+}
+
+// socketCallProto performs the protobuf-encoded types.HostSocketRequest in
+// reqBytes.
+func socketCallProto(state *State, reqBytes []byte) ([]byte, error) {
+	var req types.HostSocketRequest
+	if err := proto.Unmarshal(reqBytes, &req); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	hostResp := doSocket(state, HostSocketRequest{
+		Operation: req.Operation,
+		Address:   req.Address,
+		FD:        int(req.Fd),
+		Data:      req.Data,
+		Size:      int(req.Size),
+		Network:   req.Network,
+		TimeoutMs: int(req.TimeoutMs),
+	})
+	respBytes, err := proto.Marshal(&types.HostSocketResponse{
+		Error:     hostResp.Error,
+		Code:      hostResp.Code,
+		Fd:        int32(hostResp.FD),
+		Data:      hostResp.Data,
+		BytesRead: int32(hostResp.BytesRead),
+		BytesSent: int32(hostResp.BytesSent),
+		Address:   hostResp.Address,
+		Addresses: hostResp.Addresses,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response: %w", err)
+	}
+	return respBytes, nil
+}
+
+func headersFromProto(headers map[string]*types.HeaderFields) map[string][]string {
+	out := make(map[string][]string, len(headers))
+	for name, values := range headers {
+		out[name] = values.GetFields()
+	}
+	return out
+}
+
+func headersToProto(headers map[string][]string) map[string]*types.HeaderFields {
+	out := make(map[string]*types.HeaderFields, len(headers))
+	for name, values := range headers {
+		out[name] = &types.HeaderFields{Fields: values}
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxSecretNameBytes bounds the length of a secret name.
+	maxSecretNameBytes = 128
+	// maxMissingSecretReads bounds the names a session's reads are audited
+	// under when the deployment has no secret of that name. Reads of further
+	// unknown names are merged into a single SecretRead without a name.
+	maxMissingSecretReads = 32
+)
+
+var (
+	// ErrSecretNotFound means the deployment has no secret of that name.
+	ErrSecretNotFound = errors.New("secret not found")
+	// ErrSecretsUnavailable means there is no secret store, or it can't
+	// decrypt. Stores wrap their failures in it.
+	ErrSecretsUnavailable = errors.New("secrets unavailable")
+)
+
+// SecretStore is the storage behind host_secret_get. A session loads the
+// deployment's secrets from it once, on the first read, and reports the
+// reads made when it ends, so implementations are the place to audit
+// access. They must be safe for concurrent use.
+type SecretStore interface {
+	// LoadSecrets returns the plaintext of all of the deployment's secrets.
+	// ctx is the execution's context.
+	LoadSecrets(ctx context.Context, deploymentID uuid.UUID) (map[string][]byte, error)
+	// AuditSecrets records the reads made by a session. ctx is the context
+	// of its last execution.
+	AuditSecrets(ctx context.Context, deploymentID uuid.UUID, reads []SecretRead)
+}
+
+// SecretRead counts the reads of one secret in a session.
+type SecretRead struct {
+	Name string
+	// Err is nil if the value was handed over, otherwise what the guest got
+	// instead: ErrSecretNotFound, or the error the secrets failed to load
+	// with.
+	Err   error
+	Count int
+}
+
+// sessionSecrets holds the secrets a session loaded and the reads not yet
+// audited. It is safe for concurrent use.
+type sessionSecrets struct {
+	mu      sync.Mutex
+	loaded  bool
+	values  map[string][]byte
+	err     error
+	reads   map[string]*SecretRead
+	missing int
+	// other counts the reads merged because of maxMissingSecretReads.
+	other int
+}
+
+// get returns the secret name, loading the session's secrets from store
+// the first time. A failed load is not retried within the session.
+func (s *sessionSecrets) get(ctx context.Context, store SecretStore, deploymentID uuid.UUID, name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loaded {
+		s.values, s.err = store.LoadSecrets(ctx, deploymentID)
+		s.loaded = true
+	}
+	value, ok := s.values[name]
+	err := s.err
+	if err == nil && !ok {
+		err = ErrSecretNotFound
+	}
+	s.record(name, err)
+	return value, err
+}
+
+func (s *sessionSecrets) record(name string, err error) {
+	if read, ok := s.reads[name]; ok {
+		read.Count++
+		return
+	}
+	if errors.Is(err, ErrSecretNotFound) {
+		if s.missing >= maxMissingSecretReads {
+			s.other++
+			return
+		}
+		s.missing++
+	}
+	if s.reads == nil {
+		s.reads = make(map[string]*SecretRead)
+	}
+	s.reads[name] = &SecretRead{Name: name, Err: err, Count: 1}
+}
+
+// drain returns the reads made so far, sorted by name, and starts over.
+func (s *sessionSecrets) drain() []SecretRead {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reads := make([]SecretRead, 0, len(s.reads)+1)
+	for _, read := range s.reads {
+		reads = append(reads, *read)
+	}
+	sort.Slice(reads, func(i, j int) bool { return reads[i].Name < reads[j].Name })
+	if s.other > 0 {
+		reads = append(reads, SecretRead{Err: ErrSecretNotFound, Count: s.other})
+	}
+	s.reads, s.missing, s.other = nil, 0, 0
+	return reads
+}
+
+func secretGet(state *State, req []byte) ([]byte, error) {
+	if len(req) == 0 || len(req) > maxSecretNameBytes {
+		return nil, fmt.Errorf("%w: secret name must be 1 to %d bytes", errInvalidRequest, maxSecretNameBytes)
+	}
+	if state.Secrets == nil {
+		return nil, ErrSecretsUnavailable
+	}
+	return state.secrets.get(state.Context(), state.Secrets, state.DeploymentID, string(req))
+}
+
+// LinkSecretFunctions attaches the secret host function to the Wasmtime
+// linker:
+//
+//	host_secret_get(namePtr, nameLen, respPtr, respLen, handlePtr i32) -> i32
+//
+// It hands back the plaintext of the deployment's secret like wrapCall does,
+// or returns ErrCodeNotFound if there is none, and ErrCodeUnavailable when
+// secrets can't be read.
+func LinkSecretFunctions(linker *wasmtime.Linker) error {
+	return linker.FuncWrap("env", "host_secret_get", wrapCall("host_secret_get", secretGet))
+}
@@ -0,0 +1,95 @@
+package host_functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakeSecretStore struct {
+	values map[string][]byte
+	err    error
+	loads  int
+	reads  []SecretRead
+}
+
+func (f *fakeSecretStore) LoadSecrets(ctx context.Context, deploymentID uuid.UUID) (map[string][]byte, error) {
+	f.loads++
+	return f.values, f.err
+}
+
+func (f *fakeSecretStore) AuditSecrets(ctx context.Context, deploymentID uuid.UUID, reads []SecretRead) {
+	f.reads = append(f.reads, reads...)
+}
+
+func TestSecretGetLoadsOnce(t *testing.T) {
+	store := &fakeSecretStore{values: map[string][]byte{"key": []byte("value"), "empty": {}}}
+	state := NewState(uuid.New(), nil)
+	state.Secrets = store
+
+	for range 3 {
+		if value, err := secretGet(state, []byte("key")); err != nil || string(value) != "value" {
+			t.Fatalf("secretGet(key) = %q, %v", value, err)
+		}
+	}
+	if _, err := secretGet(state, []byte("empty")); err != nil {
+		t.Fatalf("secretGet(empty) = %v", err)
+	}
+	for i := range maxMissingSecretReads + 5 {
+		if _, err := secretGet(state, fmt.Appendf(nil, "missing%d", i)); !errors.Is(err, ErrSecretNotFound) {
+			t.Fatalf("secretGet(missing%d) = %v, want ErrSecretNotFound", i, err)
+		}
+	}
+	if store.loads != 1 {
+		t.Errorf("secrets loaded %d times, want once", store.loads)
+	}
+
+	if len(store.reads) != 0 {
+		t.Fatalf("reads audited before the session ended: %v", store.reads)
+	}
+	state.Close()
+	reads := make(map[string]SecretRead)
+	for _, read := range store.reads {
+		reads[read.Name] = read
+	}
+	if read := reads["key"]; read.Count != 3 || read.Err != nil {
+		t.Errorf("audited key as %+v, want 3 granted reads", read)
+	}
+	if read := reads["empty"]; read.Count != 1 || read.Err != nil {
+		t.Errorf("audited empty as %+v, want 1 granted read", read)
+	}
+	if read := reads[""]; read.Count != 5 || !errors.Is(read.Err, ErrSecretNotFound) {
+		t.Errorf("audited the merged reads as %+v, want 5 not found", read)
+	}
+	if want := 2 + maxMissingSecretReads + 1; len(store.reads) != want {
+		t.Errorf("audited %d records, want %d", len(store.reads), want)
+	}
+
+	state.Close()
+	if want := 2 + maxMissingSecretReads + 1; len(store.reads) != want {
+		t.Errorf("closing again audited %d more records", len(store.reads)-want)
+	}
+}
+
+func TestSecretGetLoadFailure(t *testing.T) {
+	loadErr := fmt.Errorf("%w: no key", ErrSecretsUnavailable)
+	store := &fakeSecretStore{err: loadErr}
+	state := NewState(uuid.New(), nil)
+	state.Secrets = store
+
+	for range 2 {
+		if _, err := secretGet(state, []byte("key")); !errors.Is(err, ErrSecretsUnavailable) {
+			t.Fatalf("secretGet = %v, want ErrSecretsUnavailable", err)
+		}
+	}
+	if store.loads != 1 {
+		t.Errorf("failed load retried: %d loads", store.loads)
+	}
+	state.Close()
+	if len(store.reads) != 1 || store.reads[0].Count != 2 || !errors.Is(store.reads[0].Err, ErrSecretsUnavailable) {
+		t.Errorf("audited %+v, want 2 failed reads of key", store.reads)
+	}
+}
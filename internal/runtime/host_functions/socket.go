@@ -3,272 +3,438 @@
 package host_functions
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bytecodealliance/wasmtime-go/v41"
 )
 
-// DefineLegacyWasiSockets adds legacy WASI preview 1 socket functions to the linker
-func DefineLegacyWasiSockets(linker *wasmtime.Linker) error {
-	// Define the sock_setsockopt function that was requested
-	// This is a stub implementation that returns success (0) for now
-	// In a real implementation, this would need to actually set socket options
-	sockSetSockOptFunc := func(caller *wasmtime.Caller, fd, level, optname, optval, optlen int32) int32 {
-		// For now, return 0 (success) to allow the WASM module to continue
-		// In a production implementation, this would need to actually handle socket options
-		return 0
-	}
+// maxSockets bounds the sockets a session may hold open.
+const maxSockets = 64
 
-	// Add the function to the linker under the expected name
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_setsockopt", sockSetSockOptFunc); err != nil {
-		return fmt.Errorf("failed to define sock_setsockopt: %w", err)
-	}
+// TooManySocketsCode is reported to the guest when a socket can't be opened
+// because the session already holds maxSockets.
+const TooManySocketsCode = "too_many_sockets"
 
-	// Add other common socket functions that might be expected by legacy WASI modules
-	sockGetSockOptFunc := func(caller *wasmtime.Caller, fd, level, optname, optval, optlen int32) int32 {
-		// For now, return 0 (success) to allow the WASM module to continue
-		return 0
-	}
+// errTooManySockets is the error reported with TooManySocketsCode.
+var errTooManySockets = errors.New("too many open sockets")
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_getsockopt", sockGetSockOptFunc); err != nil {
-		return fmt.Errorf("failed to define sock_getsockopt: %w", err)
-	}
+// HostSocketRequest represents the structure received from the guest for socket operations.
+type HostSocketRequest struct {
+	Operation string `json:"operation"`  // "dial", "read", "write", "sendto", "recvfrom", "resolve", "close"
+	Address   string `json:"address"`    // host:port for dial and sendto, host for resolve
+	FD        int    `json:"fd"`         // file descriptor for read/write/sendto/recvfrom/close
+	Data      []byte `json:"data"`       // data to write or send
+	Size      int    `json:"size"`       // size to read
+	Network   string `json:"network"`    // tcp (default) or udp for dial; ip4 or ip6 to narrow resolve
+	TimeoutMs int    `json:"timeout_ms"` // how long read and recvfrom may wait; 0 waits until the execution's deadline
+}
+
+// HostSocketResponse represents the structure sent from the host back to the guest.
+type HostSocketResponse struct {
+	Error     string   `json:"error,omitempty"`
+	Code      string   `json:"code,omitempty"`       // EgressDeniedCode, TimeoutCode or TooManySocketsCode
+	FD        int      `json:"fd,omitempty"`         // for dial operations
+	Data      []byte   `json:"data,omitempty"`       // for read and recvfrom operations
+	BytesRead int      `json:"bytes_read,omitempty"` // for read and recvfrom operations
+	BytesSent int      `json:"bytes_sent,omitempty"` // for write and sendto operations
+	Address   string   `json:"address,omitempty"`    // sender of the datagram for recvfrom
+	Addresses []string `json:"addresses,omitempty"`  // A and AAAA records for resolve
+}
+
+// Connection interface
+type Connection interface {
+	Read(buffer []byte) (int, error)
+	Write(data []byte) (int, error)
+	Close() error
+}
 
-	sockBindFunc := func(caller *wasmtime.Caller, fd, addr, addrlen int32) int32 {
-		// For now, return 0 (success) to allow the WASM module to continue
-		return 0
+// dialSocket opens a socket on network, tcp when empty, connected to
+// address. A udp socket without an address stays unconnected, for sendto and
+// recvfrom.
+func dialSocket(state *State, network, address string) (Connection, error) {
+	switch network {
+	case "":
+		network = "tcp"
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, fmt.Errorf("unsupported network %q", network)
 	}
+	if strings.HasPrefix(network, "udp") && address == "" {
+		return &PacketConnection{network: network}, nil
+	}
+	conn, err := state.Network.Egress.DialContext(state.Context(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &RealConnection{conn: conn}, nil
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_bind", sockBindFunc); err != nil {
-		return fmt.Errorf("failed to define sock_bind: %w", err)
+// setReadDeadline bounds the next read from conn by timeoutMs, if positive,
+// and by the execution's deadline.
+func setReadDeadline(state *State, conn Connection, timeoutMs int) {
+	if c, ok := netConn(conn); ok {
+		c.SetReadDeadline(deadline(state.Context(), time.Duration(timeoutMs)*time.Millisecond))
 	}
+}
 
-	sockConnectFunc := func(caller *wasmtime.Caller, fd, addr, addrlen int32) int32 {
-		// For now, return 0 (success) to allow the WASM module to continue
-		return 0
+// setWriteDeadline bounds the next write to conn by the execution's
+// deadline, so a peer that stops reading can't keep the guest past it.
+func setWriteDeadline(state *State, conn Connection) {
+	if c, ok := netConn(conn); ok {
+		c.SetWriteDeadline(deadline(state.Context(), 0))
 	}
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_connect", sockConnectFunc); err != nil {
-		return fmt.Errorf("failed to define sock_connect: %w", err)
+// socketErrorCode returns the code reported to the guest for a failed
+// socket operation, logging egress refusals.
+func socketErrorCode(state *State, err error) string {
+	var netErr net.Error
+	switch {
+	case state.egressDenied("host_socket", err):
+		return EgressDeniedCode
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return TimeoutCode
+	default:
+		return ""
 	}
+}
 
-	// Add the sock_getaddrinfo function that was requested in the error
-	// This is a stub implementation that writes a null pointer to the result location
-	// and returns EAI_NONAME to indicate that the hostname could not be resolved
-	sockGetAddrInfoFunc := func(caller *wasmtime.Caller, node, service, hints, addrinfo, maxaddrs, naddrs, flags, family int32) int32 {
-		// Get the memory to write the result
-		memory := caller.GetExport("memory").Memory()
-		if memory == nil {
-			return 1 // EAI_BADFLAGS error code
-		}
+// RealConnection wraps a real network connection
+type RealConnection struct {
+	conn net.Conn
+}
 
-		// Write null pointer (0) to the result address to indicate no results
-		// This follows the expected behavior of getaddrinfo when no addresses are found
-		data := memory.UnsafeData(caller)
-		if addrinfo >= 0 && int(addrinfo)+4 <= len(data) {
-			// Write 0 to indicate no results (NULL pointer)
-			data[addrinfo] = 0
-			data[addrinfo+1] = 0
-			data[addrinfo+2] = 0
-			data[addrinfo+3] = 0
-		}
+// NewRealConnection wraps conn, such as an inbound connection handed to a
+// guest.
+func NewRealConnection(conn net.Conn) *RealConnection {
+	return &RealConnection{conn: conn}
+}
 
-		// Return EAI_NONAME (8) to indicate that the hostname could not be resolved
-		// This allows the program to handle the error gracefully rather than crash
-		return 8
-	}
+func (rc *RealConnection) Read(buffer []byte) (int, error) {
+	return rc.conn.Read(buffer)
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_getaddrinfo", sockGetAddrInfoFunc); err != nil {
-		return fmt.Errorf("failed to define sock_getaddrinfo: %w", err)
-	}
+func (rc *RealConnection) Write(data []byte) (int, error) {
+	return rc.conn.Write(data)
+}
 
-	// Add other common WASI networking functions that might be expected
-	sockOpenFunc := func(caller *wasmtime.Caller, domain, socketType, protocol int32) int32 {
-		// For now, return an error to indicate the operation is not supported
-		return 58 // ENOTSOCK error code
-	}
+func (rc *RealConnection) Close() error {
+	return rc.conn.Close()
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_open", sockOpenFunc); err != nil {
-		return fmt.Errorf("failed to define sock_open: %w", err)
-	}
+// errPacketUnbound is returned when receiving on a PacketConnection that
+// hasn't sent anything, and so can't receive anything either.
+var errPacketUnbound = errors.New("recvfrom needs a prior sendto")
+
+// PacketConnection is an unconnected UDP socket, used with sendto and
+// recvfrom. It is bound on its first sendto, to the local address the host
+// uses to reach that destination rather than to every address, and it only
+// receives datagrams from addresses it has sent to, all of which the egress
+// policy allowed. Anything else is dropped.
+type PacketConnection struct {
+	network string // udp, udp4 or udp6
+
+	mu    sync.Mutex
+	conn  *net.UDPConn
+	peers map[netip.AddrPort]bool
+}
 
-	// Add sock_listen function that might also be expected
-	sockListenFunc := func(caller *wasmtime.Caller, fd, backlog int32) int32 {
-		// For now, return an error to indicate the operation is not supported
-		return 58 // ENOTSOCK error code
+// SendTo sends data as a datagram to addr, which the caller has checked
+// against the egress policy, binding the socket first if needed.
+func (pc *PacketConnection) SendTo(data []byte, addr netip.AddrPort) (int, error) {
+	pc.mu.Lock()
+	if pc.conn == nil {
+		conn, err := listenPacketFor(pc.network, addr)
+		if err != nil {
+			pc.mu.Unlock()
+			return 0, err
+		}
+		pc.conn = conn
+		pc.peers = make(map[netip.AddrPort]bool)
 	}
+	conn := pc.conn
+	pc.peers[addr] = true
+	pc.mu.Unlock()
+	return conn.WriteToUDPAddrPort(data, addr)
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_listen", sockListenFunc); err != nil {
-		return fmt.Errorf("failed to define sock_listen: %w", err)
+// RecvFrom reads the next datagram from an address the socket has sent to
+// into buffer, dropping datagrams from anywhere else.
+func (pc *PacketConnection) RecvFrom(buffer []byte) (int, netip.AddrPort, error) {
+	conn := pc.udpConn()
+	if conn == nil {
+		return 0, netip.AddrPort{}, errPacketUnbound
 	}
-
-	// Add sock_getpeeraddr function that might also be expected
-	sockGetPeerAddrFunc := func(caller *wasmtime.Caller, fd, addr, addrlen, flags int32) int32 {
-		// For now, return an error to indicate the operation is not supported
-		return 58 // ENOTSOCK error code
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(buffer)
+		if err != nil {
+			return 0, netip.AddrPort{}, err
+		}
+		from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+		pc.mu.Lock()
+		known := pc.peers[from]
+		pc.mu.Unlock()
+		if known {
+			return n, from, nil
+		}
 	}
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_getpeeraddr", sockGetPeerAddrFunc); err != nil {
-		return fmt.Errorf("failed to define sock_getpeeraddr: %w", err)
-	}
+// udpConn returns the bound socket, or nil before the first sendto.
+func (pc *PacketConnection) udpConn() *net.UDPConn {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.conn
+}
 
-	// Add sock_getlocaladdr function that might also be expected
-	sockGetLocalAddrFunc := func(caller *wasmtime.Caller, fd, addr, addrlen, flags int32) int32 {
-		// For now, return an error to indicate the operation is not supported
-		return 58 // ENOTSOCK error code
-	}
+func (pc *PacketConnection) Read(buffer []byte) (int, error) {
+	n, _, err := pc.RecvFrom(buffer)
+	return n, err
+}
 
-	if err := linker.FuncWrap("wasi_snapshot_preview1", "sock_getlocaladdr", sockGetLocalAddrFunc); err != nil {
-		return fmt.Errorf("failed to define sock_getlocaladdr: %w", err)
-	}
+func (pc *PacketConnection) Write(data []byte) (int, error) {
+	return 0, errors.New("write needs a connected socket; use sendto")
+}
 
+func (pc *PacketConnection) Close() error {
+	if conn := pc.udpConn(); conn != nil {
+		return conn.Close()
+	}
 	return nil
 }
 
-// HostSocketRequest represents the structure received from the guest for socket operations.
-type HostSocketRequest struct {
-	Operation string `json:"operation"` // "dial", "read", "write", "close"
-	Address   string `json:"address"`   // host:port for dial
-	FD        int    `json:"fd"`        // file descriptor for read/write/close
-	Data      []byte `json:"data"`      // data to write
-	Size      int    `json:"size"`      // size to read
-	Network   string `json:"network"`   // network type (tcp, udp, etc.)
+// listenPacketFor opens a UDP socket on network bound to the local address
+// routing to peer. Connecting a UDP socket picks that address without
+// sending anything.
+func listenPacketFor(network string, peer netip.AddrPort) (*net.UDPConn, error) {
+	probe, err := net.DialUDP(network, nil, net.UDPAddrFromAddrPort(peer))
+	if err != nil {
+		return nil, err
+	}
+	local := probe.LocalAddr().(*net.UDPAddr).AddrPort().Addr()
+	probe.Close()
+	return net.ListenUDP(network, net.UDPAddrFromAddrPort(netip.AddrPortFrom(local, 0)))
 }
 
-// HostSocketResponse represents the structure sent from the host back to the guest.
-type HostSocketResponse struct {
-	Error     string `json:"error,omitempty"`
-	FD        int    `json:"fd,omitempty"`         // for dial operations
-	Data      []byte `json:"data,omitempty"`       // for read operation
-	BytesRead int    `json:"bytes_read,omitempty"` // for read operation
-	BytesSent int    `json:"bytes_sent,omitempty"` // for write operation
+// firstSocketFD is the first descriptor handed out by a SocketTable, well
+// above those Wasmtime assigns to files, so a guest can't mistake one for
+// the other.
+const firstSocketFD = 1 << 16
+
+// SocketTable holds the connections opened by one session. Descriptors are
+// only meaningful within the table, so a guest can't reach another guest's
+// connections. It is safe for concurrent use.
+type SocketTable struct {
+	mu     sync.Mutex
+	conns  map[int]Connection
+	nextFD int
 }
 
-// Connection interface
-type Connection interface {
-	Read(buffer []byte) (int, error)
-	Write(data []byte) (int, error)
-	Close() error
+// NewSocketTable creates an empty table.
+func NewSocketTable() *SocketTable {
+	return &SocketTable{
+		conns:  make(map[int]Connection),
+		nextFD: firstSocketFD,
+	}
 }
 
-// RealConnection wraps a real network connection
-type RealConnection struct {
-	conn net.Conn
+// Add registers conn and returns its descriptor, or false if too many are
+// open.
+func (t *SocketTable) Add(conn Connection) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.conns) >= maxSockets {
+		return 0, false
+	}
+	fd := t.nextFD
+	t.nextFD++
+	t.conns[fd] = conn
+	return fd, true
 }
 
-func (rc *RealConnection) Read(buffer []byte) (int, error) {
-	return rc.conn.Read(buffer)
+// Get returns the connection registered under fd.
+func (t *SocketTable) Get(fd int) (Connection, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn, ok := t.conns[fd]
+	return conn, ok
 }
 
-func (rc *RealConnection) Write(data []byte) (int, error) {
-	return rc.conn.Write(data)
+// Remove unregisters and returns the connection under fd.
+func (t *SocketTable) Remove(fd int) (Connection, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	conn, ok := t.conns[fd]
+	delete(t.conns, fd)
+	return conn, ok
 }
 
-func (rc *RealConnection) Close() error {
-	return rc.conn.Close()
+// Close closes every connection still open and empties the table.
+func (t *SocketTable) Close() error {
+	t.mu.Lock()
+	conns := t.conns
+	t.conns = make(map[int]Connection)
+	t.mu.Unlock()
+
+	var errs []error
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// ConnectionStore holds all connections
-type ConnectionStore struct {
-	realConns map[int]Connection
-	nextFD    int
+// LinkSocketFunctions attaches the socket host functions to the Wasmtime
+// linker: host_socket_call_v2 with protobuf messages and host_socket_call
+// with JSON, both with the two-phase ABI of wrapCall, and
+// host_socket_operation with JSON and the legacy ABI.
+func LinkSocketFunctions(linker *wasmtime.Linker) error {
+	if err := linker.FuncWrap("env", "host_socket_call_v2", wrapCall("host_socket_call_v2", socketCallProto)); err != nil {
+		return err
+	}
+	if err := linker.FuncWrap("env", "host_socket_call", wrapCall("host_socket_call", socketCall)); err != nil {
+		return err
+	}
+	return linker.FuncWrap("env", "host_socket_operation", wrapLegacyCall("host_socket_operation", socketCall))
 }
 
-var connStore = &ConnectionStore{
-	realConns: make(map[int]Connection),
-	nextFD:    100,
+// socketCall performs the JSON-encoded socket operation in reqBytes.
+func socketCall(state *State, reqBytes []byte) ([]byte, error) {
+	var hostReq HostSocketRequest
+	if err := json.Unmarshal(reqBytes, &hostReq); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidRequest, err)
+	}
+	respBytes, err := json.Marshal(doSocket(state, hostReq))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal response JSON: %w", err)
+	}
+	return respBytes, nil
 }
 
-// LinkSocketFunctions attaches the socket host functions to the Wasmtime linker.
-func LinkSocketFunctions(store *wasmtime.Store, linker *wasmtime.Linker) error {
-	return linker.DefineFunc(store, "env", "host_socket_operation", func(caller *wasmtime.Caller, reqPtr, reqLen, respPtr, respLen int32) int32 {
-		memory := caller.GetExport("memory").Memory()
-		if memory == nil {
-			log.Println("host_socket_operation: failed to get memory export")
-			return 0
-		}
+// doSocket performs hostReq on the session's connections.
+func doSocket(state *State, hostReq HostSocketRequest) HostSocketResponse {
+	sockets := state.Sockets
 
-		// Read the request JSON from guest memory.
-		reqBytes := memory.UnsafeData(store)[reqPtr : reqPtr+reqLen]
+	// Perform the requested socket operation
+	var hostResp HostSocketResponse
 
-		// Unmarshal the request.
-		var hostReq HostSocketRequest
-		if err := json.Unmarshal(reqBytes, &hostReq); err != nil {
-			log.Printf("host_socket_operation: failed to unmarshal request JSON: %v\n", err)
-			return 0
+	switch hostReq.Operation {
+	case "dial":
+		conn, err := dialSocket(state, hostReq.Network, hostReq.Address)
+		if err != nil {
+			hostResp.Error = err.Error()
+			hostResp.Code = socketErrorCode(state, err)
+		} else if fd, ok := sockets.Add(conn); ok {
+			hostResp.FD = fd
+		} else {
+			conn.Close()
+			hostResp.Error = errTooManySockets.Error()
+			hostResp.Code = TooManySocketsCode
 		}
-
-		// Perform the requested socket operation
-		var hostResp HostSocketResponse
-
-		switch hostReq.Operation {
-		case "dial":
-			conn, err := net.Dial("tcp", hostReq.Address)
+	case "read":
+		conn, exists := sockets.Get(hostReq.FD)
+		if !exists {
+			hostResp.Error = "invalid file descriptor"
+		} else if hostReq.Size < 0 {
+			hostResp.Error = "invalid read size"
+		} else {
+			setReadDeadline(state, conn, hostReq.TimeoutMs)
+			buffer := make([]byte, min(hostReq.Size, maxRecvChunk))
+			n, err := conn.Read(buffer)
 			if err != nil {
 				hostResp.Error = err.Error()
+				hostResp.Code = socketErrorCode(state, err)
 			} else {
-				fd := connStore.nextFD
-				connStore.nextFD++
-				connStore.realConns[fd] = &RealConnection{conn: conn}
-				hostResp.FD = fd
+				hostResp.Data = buffer[:n]
+				hostResp.BytesRead = n
 			}
-		case "read":
-			conn, exists := connStore.realConns[hostReq.FD]
-			if !exists {
-				hostResp.Error = "invalid file descriptor"
-			} else {
-				buffer := make([]byte, hostReq.Size)
-				n, err := conn.Read(buffer)
-				if err != nil {
-					hostResp.Error = err.Error()
-				} else {
-					hostResp.Data = buffer[:n]
-					hostResp.BytesRead = n
-				}
-			}
-		case "write":
-			conn, exists := connStore.realConns[hostReq.FD]
-			if !exists {
-				hostResp.Error = "invalid file descriptor"
-			} else {
-				n, err := conn.Write(hostReq.Data)
-				if err != nil {
-					hostResp.Error = err.Error()
-				} else {
-					hostResp.BytesSent = n
-				}
-			}
-		case "close":
-			if conn, exists := connStore.realConns[hostReq.FD]; exists {
-				delete(connStore.realConns, hostReq.FD)
-				conn.Close()
+		}
+	case "sendto":
+		conn, exists := sockets.Get(hostReq.FD)
+		packetConn, isPacket := conn.(*PacketConnection)
+		if !exists {
+			hostResp.Error = "invalid file descriptor"
+		} else if !isPacket {
+			hostResp.Error = "sendto needs an unconnected udp socket"
+		} else if addr, err := state.Network.Egress.Resolve(state.Context(), packetConn.network, hostReq.Address); err != nil {
+			hostResp.Error = err.Error()
+			hostResp.Code = socketErrorCode(state, err)
+		} else if n, err := packetConn.SendTo(hostReq.Data, addr); err != nil {
+			hostResp.Error = err.Error()
+		} else {
+			hostResp.BytesSent = n
+		}
+	case "recvfrom":
+		conn, exists := sockets.Get(hostReq.FD)
+		packetConn, isPacket := conn.(*PacketConnection)
+		if !exists {
+			hostResp.Error = "invalid file descriptor"
+		} else if !isPacket {
+			hostResp.Error = "recvfrom needs an unconnected udp socket"
+		} else if hostReq.Size < 0 {
+			hostResp.Error = "invalid read size"
+		} else {
+			setReadDeadline(state, conn, hostReq.TimeoutMs)
+			buffer := make([]byte, min(hostReq.Size, maxRecvChunk))
+			n, from, err := packetConn.RecvFrom(buffer)
+			if err != nil {
+				hostResp.Error = err.Error()
+				hostResp.Code = socketErrorCode(state, err)
 			} else {
-				hostResp.Error = "invalid file descriptor"
+				hostResp.Data = buffer[:n]
+				hostResp.BytesRead = n
+				hostResp.Address = from.String()
 			}
-		default:
-			hostResp.Error = "unknown operation"
 		}
-
-		// Marshal the response to JSON.
-		respBytes, err := json.Marshal(hostResp)
+	case "resolve":
+		network := hostReq.Network
+		if network == "" {
+			network = "ip"
+		}
+		if network != "ip" && network != "ip4" && network != "ip6" {
+			hostResp.Error = "unsupported network"
+			break
+		}
+		addrs, err := state.Network.Egress.LookupHost(state.Context(), network, hostReq.Address)
 		if err != nil {
-			log.Printf("host_socket_operation: failed to marshal response JSON: %v\n", err)
-			return 0
+			hostResp.Error = err.Error()
+			hostResp.Code = socketErrorCode(state, err)
+			break
 		}
-
-		// Check if the result buffer is large enough.
-		if int32(len(respBytes)) > respLen {
-			log.Printf("host_socket_operation: result buffer too small. Needed %d, have %d\n", len(respBytes), respLen)
-			return 0
+		for _, addr := range addrs {
+			hostResp.Addresses = append(hostResp.Addresses, addr.Unmap().String())
 		}
-
-		// Write the JSON response back into the guest's memory buffer.
-		copy(memory.UnsafeData(store)[respPtr:respPtr+int32(len(respBytes))], respBytes)
-
-		// Return the length of the JSON response written.
-		return int32(len(respBytes))
-	})
+	case "write":
+		conn, exists := sockets.Get(hostReq.FD)
+		if !exists {
+			hostResp.Error = "invalid file descriptor"
+		} else {
+			setWriteDeadline(state, conn)
+			n, err := conn.Write(hostReq.Data)
+			if err != nil {
+				hostResp.Error = err.Error()
+				hostResp.Code = socketErrorCode(state, err)
+			} else {
+				hostResp.BytesSent = n
+			}
+		}
+	case "close":
+		if conn, exists := sockets.Remove(hostReq.FD); exists {
+			conn.Close()
+		} else {
+			hostResp.Error = "invalid file descriptor"
+		}
+	default:
+		hostResp.Error = "unknown operation"
+	}
+	return hostResp
 }
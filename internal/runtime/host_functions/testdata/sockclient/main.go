@@ -0,0 +1,145 @@
+//go:build wasip1
+
+// Command sockclient is the Go counterpart of sockclient.c, for toolchains
+// without a C compiler for WASI.
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"unsafe"
+)
+
+type wasiAddress struct {
+	buf  uint32
+	size uint32
+}
+
+type iovec struct {
+	buf uint32
+	len uint32
+}
+
+type wasiSockaddr struct {
+	family  uint8
+	_       [3]byte
+	dataLen uint32
+	data    uint32
+}
+
+type wasiAddrinfo struct {
+	flags        uint16
+	family       uint8
+	socktype     uint8
+	protocol     uint8
+	_            [3]byte
+	addrlen      uint32
+	addr         uint32
+	canonname    uint32
+	canonnamelen uint32
+	next         uint32
+}
+
+const (
+	afInet4    = 1
+	sockStream = 2
+	shutBoth   = 3
+	maxResults = 4
+)
+
+//go:wasmimport wasi_snapshot_preview1 sock_open
+func sockOpen(family, typ, fdPtr uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_connect
+func sockConnect(fd, addrPtr, port uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_send
+func sockSend(fd, iovsPtr, iovsLen, flags, sentPtr uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_recv
+func sockRecv(fd, iovsPtr, iovsLen, flags, receivedPtr, oflagsPtr uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_shutdown
+func sockShutdown(fd, how uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_getpeeraddr
+func sockGetPeerAddr(fd, addrPtr, typePtr, portPtr uint32) uint32
+
+//go:wasmimport wasi_snapshot_preview1 sock_getaddrinfo
+func sockGetAddrInfo(nodePtr, nodeLen, servicePtr, serviceLen, hintsPtr, resPtr, maxResults, resLenPtr uint32) uint32
+
+func ptr[T any](p *T) uint32 {
+	return uint32(uintptr(unsafe.Pointer(p)))
+}
+
+func main() {
+	if len(os.Args) != 4 {
+		fmt.Fprintf(os.Stderr, "usage: %s <host> <port> <message>\n", os.Args[0])
+		os.Exit(2)
+	}
+	host, service, message := []byte(os.Args[1]), []byte(os.Args[2]), []byte(os.Args[3])
+	// NUL-terminated, so even an empty argument has a byte to point at.
+	host, service = append(host, 0), append(service, 0)
+
+	// Chain of preallocated results for sock_getaddrinfo to fill in.
+	var infos [maxResults]wasiAddrinfo
+	var sockaddrs [maxResults]wasiSockaddr
+	var data [maxResults][26]byte
+	for i := range infos {
+		sockaddrs[i] = wasiSockaddr{dataLen: uint32(len(data[i])), data: ptr(&data[i][0])}
+		infos[i].addr = ptr(&sockaddrs[i])
+		if i+1 < maxResults {
+			infos[i].next = ptr(&infos[i+1])
+		}
+	}
+	hints := wasiAddrinfo{family: afInet4, socktype: sockStream}
+	res := ptr(&infos[0])
+	var count uint32
+	if err := sockGetAddrInfo(ptr(&host[0]), uint32(len(host)-1), ptr(&service[0]), uint32(len(service)-1), ptr(&hints), ptr(&res), maxResults, ptr(&count)); err != 0 {
+		fail("getaddrinfo", err)
+	}
+	sa := data[0]
+	port := uint32(sa[0])<<8 | uint32(sa[1])
+	fmt.Printf("resolved %d.%d.%d.%d:%d\n", sa[2], sa[3], sa[4], sa[5], port)
+
+	var fd uint32
+	if err := sockOpen(afInet4, sockStream, ptr(&fd)); err != 0 {
+		fail("open", err)
+	}
+	ip := [4]byte{sa[2], sa[3], sa[4], sa[5]}
+	addr := wasiAddress{buf: ptr(&ip[0]), size: uint32(len(ip))}
+	if err := sockConnect(fd, ptr(&addr), port); err != 0 {
+		fail("connect", err)
+	}
+
+	var peer [4]byte
+	peerAddr := wasiAddress{buf: ptr(&peer[0]), size: uint32(len(peer))}
+	var peerType, peerPort uint32
+	if err := sockGetPeerAddr(fd, ptr(&peerAddr), ptr(&peerType), ptr(&peerPort)); err != 0 {
+		fail("getpeeraddr", err)
+	}
+	fmt.Printf("peer port %d\n", peerPort)
+
+	out := iovec{buf: ptr(&message[0]), len: uint32(len(message))}
+	var sent uint32
+	if err := sockSend(fd, ptr(&out), 1, 0, ptr(&sent)); err != 0 {
+		fail("send", err)
+	}
+
+	var buf [256]byte
+	in := iovec{buf: ptr(&buf[0]), len: uint32(len(buf))}
+	var received uint32
+	var oflags uint16
+	if err := sockRecv(fd, ptr(&in), 1, 0, ptr(&received), ptr(&oflags)); err != 0 {
+		fail("recv", err)
+	}
+	fmt.Printf("echo: %s\n", buf[:received])
+
+	sockShutdown(fd, shutBoth)
+}
+
+func fail(op string, errno uint32) {
+	fmt.Println(op + ": errno " + strconv.FormatUint(uint64(errno), 10))
+	os.Exit(1)
+}
@@ -0,0 +1,124 @@
+// sockclient resolves a host, connects to it with the WasmEdge socket
+// extension of WASI, sends a message and prints the reply.
+//
+// Usage: sockclient <host> <port> <message>
+//
+// Build: clang --target=wasm32-wasip1 -O2 -o sockclient.wasm sockclient.c
+
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define WASI_IMPORT(name) \
+    __attribute__((import_module("wasi_snapshot_preview1"), import_name(name)))
+
+typedef struct {
+    uint8_t *buf;
+    uint32_t size;
+} wasi_address;
+
+typedef struct {
+    uint8_t *buf;
+    uint32_t len;
+} iovec;
+
+typedef struct {
+    uint8_t family;
+    uint32_t data_len;
+    uint8_t *data;
+} wasi_sockaddr;
+
+typedef struct wasi_addrinfo {
+    uint16_t flags;
+    uint8_t family;
+    uint8_t socktype;
+    uint8_t protocol;
+    uint32_t addrlen;
+    wasi_sockaddr *addr;
+    char *canonname;
+    uint32_t canonnamelen;
+    struct wasi_addrinfo *next;
+} wasi_addrinfo;
+
+enum { AF_INET4 = 1, SOCK_STREAM_ = 2, SHUT_BOTH = 3, MAX_RESULTS = 4 };
+
+WASI_IMPORT("sock_open") uint32_t sock_open(uint32_t family, uint32_t type, uint32_t *fd);
+WASI_IMPORT("sock_connect") uint32_t sock_connect(uint32_t fd, wasi_address *addr, uint32_t port);
+WASI_IMPORT("sock_send") uint32_t sock_send(uint32_t fd, iovec *iovs, uint32_t iovs_len, uint32_t flags, uint32_t *sent);
+WASI_IMPORT("sock_recv") uint32_t sock_recv(uint32_t fd, iovec *iovs, uint32_t iovs_len, uint32_t flags, uint32_t *received, uint16_t *oflags);
+WASI_IMPORT("sock_shutdown") uint32_t sock_shutdown(uint32_t fd, uint32_t how);
+WASI_IMPORT("sock_getpeeraddr") uint32_t sock_getpeeraddr(uint32_t fd, wasi_address *addr, uint32_t *type, uint32_t *port);
+WASI_IMPORT("sock_getaddrinfo") uint32_t sock_getaddrinfo(const char *node, uint32_t node_len, const char *service, uint32_t service_len, const wasi_addrinfo *hints, wasi_addrinfo **res, uint32_t max_results, uint32_t *res_len);
+
+int main(int argc, char **argv) {
+    if (argc != 4) {
+        fprintf(stderr, "usage: %s <host> <port> <message>\n", argv[0]);
+        return 2;
+    }
+    const char *host = argv[1], *service = argv[2], *message = argv[3];
+
+    // Chain of preallocated results for sock_getaddrinfo to fill in.
+    wasi_addrinfo infos[MAX_RESULTS];
+    wasi_sockaddr sockaddrs[MAX_RESULTS];
+    uint8_t data[MAX_RESULTS][26];
+    memset(infos, 0, sizeof(infos));
+    for (int i = 0; i < MAX_RESULTS; i++) {
+        sockaddrs[i] = (wasi_sockaddr){.data_len = sizeof(data[i]), .data = data[i]};
+        infos[i].addr = &sockaddrs[i];
+        infos[i].next = i + 1 < MAX_RESULTS ? &infos[i + 1] : NULL;
+    }
+    wasi_addrinfo hints = {.family = AF_INET4, .socktype = SOCK_STREAM_};
+    wasi_addrinfo *res = infos;
+    uint32_t count = 0;
+    uint32_t err = sock_getaddrinfo(host, strlen(host), service, strlen(service), &hints, &res, MAX_RESULTS, &count);
+    if (err != 0) {
+        printf("getaddrinfo: errno %u\n", err);
+        return 1;
+    }
+    uint8_t *sa = infos[0].addr->data;
+    uint32_t port = (sa[0] << 8) | sa[1];
+    printf("resolved %u.%u.%u.%u:%u\n", sa[2], sa[3], sa[4], sa[5], port);
+
+    uint32_t fd;
+    if ((err = sock_open(AF_INET4, SOCK_STREAM_, &fd)) != 0) {
+        printf("open: errno %u\n", err);
+        return 1;
+    }
+    uint8_t ip[4] = {sa[2], sa[3], sa[4], sa[5]};
+    wasi_address addr = {ip, sizeof(ip)};
+    if ((err = sock_connect(fd, &addr, port)) != 0) {
+        printf("connect: errno %u\n", err);
+        return 1;
+    }
+
+    uint8_t peer[4];
+    wasi_address peer_addr = {peer, sizeof(peer)};
+    uint32_t peer_type, peer_port;
+    if ((err = sock_getpeeraddr(fd, &peer_addr, &peer_type, &peer_port)) != 0) {
+        printf("getpeeraddr: errno %u\n", err);
+        return 1;
+    }
+    printf("peer port %u\n", peer_port);
+
+    iovec out = {(uint8_t *)message, strlen(message)};
+    uint32_t sent;
+    if ((err = sock_send(fd, &out, 1, 0, &sent)) != 0) {
+        printf("send: errno %u\n", err);
+        return 1;
+    }
+
+    uint8_t buf[256];
+    iovec in = {buf, sizeof(buf) - 1};
+    uint32_t received;
+    uint16_t oflags;
+    if ((err = sock_recv(fd, &in, 1, 0, &received, &oflags)) != 0) {
+        printf("recv: errno %u\n", err);
+        return 1;
+    }
+    buf[received] = 0;
+    printf("echo: %s\n", buf);
+
+    sock_shutdown(fd, SHUT_BOTH);
+    return 0;
+}
@@ -0,0 +1,822 @@
+//go:build !wasip1
+
+package host_functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+// WASI errno values returned by the socket functions.
+const (
+	errnoSuccess      int32 = 0
+	errnoAcces        int32 = 2
+	errnoAfNoSupport  int32 = 5
+	errnoBadf         int32 = 8
+	errnoConnRefused  int32 = 14
+	errnoConnReset    int32 = 15
+	errnoFault        int32 = 21
+	errnoHostUnreach  int32 = 23
+	errnoInval        int32 = 28
+	errnoIO           int32 = 29
+	errnoIsConn       int32 = 30
+	errnoMFile        int32 = 33
+	errnoNoProtoOpt   int32 = 50
+	errnoNotConn      int32 = 53
+	errnoNotSock      int32 = 57
+	errnoNotSup       int32 = 58
+	errnoPipe         int32 = 64
+	errnoTimedOut     int32 = 73
+	errnoAIFail       int32 = 80 // WasmEdge extension: name resolution failed
+	errnoAINoName     int32 = 84 // WasmEdge extension: unknown host
+	errnoAIService    int32 = 85 // WasmEdge extension: unknown service
+	errnoAISockType   int32 = 86 // WasmEdge extension: unsupported socket type
+	errnoAIAddrFamily int32 = 77 // WasmEdge extension: unsupported address family
+)
+
+// Address families, socket types and options of the WasmEdge socket ABI.
+const (
+	afUnspec uint8 = 0
+	afInet4  uint8 = 1
+	afInet6  uint8 = 2
+
+	sockAny    uint8 = 0
+	sockDgram  uint8 = 1
+	sockStream uint8 = 2
+
+	protoTCP uint8 = 1
+
+	solSocket     int32 = 0
+	soType        int32 = 1
+	soError       int32 = 2
+	soSndBuf      int32 = 5
+	soRcvBuf      int32 = 6
+	soKeepAlive   int32 = 7
+	soRcvTimeo    int32 = 11
+	soSndTimeo    int32 = 12
+	soAcceptConn  int32 = 13
+	recvWaitAll   int32 = 2
+	shutdownRead  int32 = 1
+	shutdownWrite int32 = 2
+)
+
+// maxRecvChunk bounds the bytes a single socket read returns, which is
+// enough for any UDP datagram.
+const maxRecvChunk = 64 << 10
+
+var errNotConnected = errors.New("socket is not connected")
+
+// wasiSocket is a socket opened with sock_open. It sends and receives once
+// connected.
+type wasiSocket struct {
+	family uint8
+	conn   net.Conn
+
+	keepAlive   bool
+	sendBuf     int32
+	recvBuf     int32
+	recvTimeout time.Duration
+	sendTimeout time.Duration
+}
+
+// unspecified returns the unspecified address of the socket's family.
+func (s *wasiSocket) unspecified() netip.Addr {
+	if s.family == afInet6 {
+		return netip.IPv6Unspecified()
+	}
+	return netip.IPv4Unspecified()
+}
+
+func (s *wasiSocket) Read(buffer []byte) (int, error) {
+	if s.conn == nil {
+		return 0, errNotConnected
+	}
+	return s.conn.Read(buffer)
+}
+
+func (s *wasiSocket) Write(data []byte) (int, error) {
+	if s.conn == nil {
+		return 0, errNotConnected
+	}
+	return s.conn.Write(data)
+}
+
+func (s *wasiSocket) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// netConn returns the network connection behind conn, if it has one.
+func netConn(conn Connection) (net.Conn, bool) {
+	switch c := conn.(type) {
+	case *wasiSocket:
+		return c.conn, c.conn != nil
+	case *RealConnection:
+		return c.conn, true
+	case *PacketConnection:
+		conn := c.udpConn()
+		return conn, conn != nil
+	default:
+		return nil, false
+	}
+}
+
+// deadline is when a blocking operation must give up: after timeout, if
+// set, and never past the execution's deadline.
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var d time.Time
+	if timeout > 0 {
+		d = time.Now().Add(timeout)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && (d.IsZero() || ctxDeadline.Before(d)) {
+		d = ctxDeadline
+	}
+	return d
+}
+
+// socketErrno maps a failed socket operation to a WASI errno.
+func socketErrno(err error) int32 {
+	var egressErr *EgressError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &egressErr):
+		return errnoAcces
+	case errors.Is(err, errNotConnected), errors.Is(err, net.ErrClosed):
+		return errnoNotConn
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errnoTimedOut
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errnoConnRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return errnoConnReset
+	case errors.Is(err, syscall.EPIPE):
+		return errnoPipe
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return errnoHostUnreach
+	default:
+		return errnoIO
+	}
+}
+
+// readWasiAddress decodes the address at ptr, a {buf, size u32} pair. The
+// buffer holds either the raw address, 4 bytes for IPv4 and 16 for IPv6, or
+// a little-endian u16 family followed by the address, as newer WasmEdge SDKs
+// send it.
+func readWasiAddress(data []byte, ptr int32) (netip.Addr, bool) {
+	desc, ok := guestSlice(data, ptr, 8)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	buf, ok := guestSlice(data, int32(binary.LittleEndian.Uint32(desc)), int32(binary.LittleEndian.Uint32(desc[4:])))
+	if !ok {
+		return netip.Addr{}, false
+	}
+	switch {
+	case len(buf) == 4:
+		return netip.AddrFrom4([4]byte(buf)), true
+	case len(buf) >= 6 && binary.LittleEndian.Uint16(buf) == uint16(afInet4):
+		return netip.AddrFrom4([4]byte(buf[2:6])), true
+	case len(buf) == 16:
+		return netip.AddrFrom16([16]byte(buf)), true
+	case len(buf) >= 18 && binary.LittleEndian.Uint16(buf) == uint16(afInet6):
+		return netip.AddrFrom16([16]byte(buf[2:18])), true
+	default:
+		return netip.Addr{}, false
+	}
+}
+
+// writeWasiAddress stores addr in the {buf, size u32} pair at ptr, in the
+// format readWasiAddress accepts for a buffer of that size, and its type (4
+// or 6) and port at typePtr and portPtr.
+func writeWasiAddress(data []byte, ptr, typePtr, portPtr int32, addrPort netip.AddrPort) int32 {
+	desc, ok := guestSlice(data, ptr, 8)
+	if !ok {
+		return errnoFault
+	}
+	typeBuf, ok := guestSlice(data, typePtr, 4)
+	if !ok {
+		return errnoFault
+	}
+	portBuf, ok := guestSlice(data, portPtr, 4)
+	if !ok {
+		return errnoFault
+	}
+	buf, ok := guestSlice(data, int32(binary.LittleEndian.Uint32(desc)), int32(binary.LittleEndian.Uint32(desc[4:])))
+	if !ok {
+		return errnoFault
+	}
+
+	addr := addrPort.Addr().Unmap()
+	raw := addr.AsSlice()
+	family, addrType := afInet4, uint32(4)
+	if addr.Is6() {
+		family, addrType = afInet6, 6
+	}
+	switch {
+	case len(buf) >= 2+len(raw) && len(buf) != len(raw):
+		binary.LittleEndian.PutUint16(buf, uint16(family))
+		copy(buf[2:], raw)
+	case len(buf) == len(raw):
+		copy(buf, raw)
+	default:
+		return errnoInval
+	}
+	binary.LittleEndian.PutUint32(typeBuf, addrType)
+	binary.LittleEndian.PutUint32(portBuf, uint32(addrPort.Port()))
+	return errnoSuccess
+}
+
+// readIovecs returns the guest buffers described by the iovec array at ptr.
+func readIovecs(data []byte, ptr, count int32) ([][]byte, bool) {
+	if count < 0 || int64(count)*8 > int64(len(data)) {
+		return nil, false
+	}
+	vecs, ok := guestSlice(data, ptr, count*8)
+	if !ok {
+		return nil, false
+	}
+	bufs := make([][]byte, count)
+	for i := range bufs {
+		vec := vecs[i*8:]
+		buf, ok := guestSlice(data, int32(binary.LittleEndian.Uint32(vec)), int32(binary.LittleEndian.Uint32(vec[4:])))
+		if !ok {
+			return nil, false
+		}
+		bufs[i] = buf
+	}
+	return bufs, true
+}
+
+// putUint32 stores v at ptr in guest memory.
+func putUint32(data []byte, ptr int32, v uint32) bool {
+	buf, ok := guestSlice(data, ptr, 4)
+	if ok {
+		binary.LittleEndian.PutUint32(buf, v)
+	}
+	return ok
+}
+
+// wasiSocketState returns the host state and the socket under fd.
+func wasiSocketState(caller *wasmtime.Caller, fd int32) (*State, Connection, int32) {
+	state, ok := caller.Data().(*State)
+	if !ok {
+		return nil, nil, errnoNotSup
+	}
+	conn, ok := state.Sockets.Get(int(fd))
+	if !ok {
+		return state, nil, errnoBadf
+	}
+	return state, conn, errnoSuccess
+}
+
+// DefineLegacyWasiSockets adds the WasmEdge socket extension of WASI preview
+// 1 to the linker, so that standard libraries built with WasmEdge socket
+// support work. Sockets live in the session's SocketTable, next to those of
+// host_socket_operation, and every connection goes through the deployment's
+// egress policy. WASI's own sock_recv, sock_send and sock_shutdown are
+// replaced, since they only know preopened sockets, which sessions never
+// have.
+//
+// A socket is closed by shutting down both directions, or when the session
+// ends; fd_close doesn't reach it. Listening isn't supported yet.
+func DefineLegacyWasiSockets(linker *wasmtime.Linker) error {
+	defs := []struct {
+		name string
+		fn   interface{}
+	}{
+		{"sock_open", sockOpen},
+		{"sock_bind", sockBind},
+		{"sock_connect", sockConnect},
+		{"sock_listen", sockListen},
+		{"sock_send", sockSend},
+		{"sock_recv", sockRecv},
+		{"sock_shutdown", sockShutdown},
+		{"sock_getpeeraddr", sockGetPeerAddr},
+		{"sock_getlocaladdr", sockGetLocalAddr},
+		{"sock_setsockopt", sockSetSockOpt},
+		{"sock_getsockopt", sockGetSockOpt},
+		{"sock_getaddrinfo", sockGetAddrInfo},
+	}
+	linker.AllowShadowing(true)
+	defer linker.AllowShadowing(false)
+	for _, def := range defs {
+		if err := linker.FuncWrap("wasi_snapshot_preview1", def.name, def.fn); err != nil {
+			return fmt.Errorf("failed to define %s: %w", def.name, err)
+		}
+	}
+	return nil
+}
+
+// sockOpen creates an unconnected socket and stores its descriptor at fdPtr.
+func sockOpen(caller *wasmtime.Caller, family, socketType, fdPtr int32) int32 {
+	state, ok := caller.Data().(*State)
+	if !ok {
+		return errnoNotSup
+	}
+	if _, ok := guestSlice(guestMemory(caller), fdPtr, 4); !ok {
+		return errnoFault
+	}
+	if uint8(family) != afInet4 && uint8(family) != afInet6 {
+		return errnoAfNoSupport
+	}
+	if uint8(socketType) != sockStream {
+		return errnoNotSup
+	}
+	fd, ok := state.Sockets.Add(&wasiSocket{family: uint8(family)})
+	if !ok {
+		return errnoMFile
+	}
+	putUint32(guestMemory(caller), fdPtr, uint32(fd))
+	return errnoSuccess
+}
+
+// sockBind binds a socket to the unspecified address of its family and port
+// 0, leaving the host to pick both when it connects, as it would anyway.
+// Other addresses fail with ENOTSUP, since guests may not listen or choose
+// the host's interfaces and ports.
+func sockBind(caller *wasmtime.Caller, fd, addrPtr, port int32) int32 {
+	_, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	sock, ok := conn.(*wasiSocket)
+	if !ok {
+		return errnoNotSock
+	}
+	addr, ok := readWasiAddress(guestMemory(caller), addrPtr)
+	if !ok || port < 0 || port > 65535 {
+		return errnoInval
+	}
+	if addr != sock.unspecified() || port != 0 {
+		return errnoNotSup
+	}
+	return errnoSuccess
+}
+
+// sockConnect connects a socket, subject to the egress policy.
+func sockConnect(caller *wasmtime.Caller, fd, addrPtr, port int32) int32 {
+	state, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	sock, ok := conn.(*wasiSocket)
+	if !ok {
+		return errnoNotSock
+	}
+	if sock.conn != nil {
+		return errnoIsConn
+	}
+	addr, ok := readWasiAddress(guestMemory(caller), addrPtr)
+	if !ok || port < 0 || port > 65535 {
+		return errnoInval
+	}
+	address := netip.AddrPortFrom(addr, uint16(port)).String()
+
+	ctx := state.Context()
+	if sock.sendTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sock.sendTimeout)
+		defer cancel()
+	}
+	c, err := state.Network.Egress.DialContext(ctx, "tcp", address)
+	if err != nil {
+		state.egressDenied("sock_connect", err)
+		return socketErrno(err)
+	}
+	if tcp, ok := c.(*net.TCPConn); ok {
+		tcp.SetKeepAlive(sock.keepAlive)
+		if sock.sendBuf > 0 {
+			tcp.SetWriteBuffer(int(sock.sendBuf))
+		}
+		if sock.recvBuf > 0 {
+			tcp.SetReadBuffer(int(sock.recvBuf))
+		}
+	}
+	sock.conn = c
+	return errnoSuccess
+}
+
+// sockListen is not supported yet.
+func sockListen(caller *wasmtime.Caller, fd, backlog int32) int32 {
+	return errnoNotSup
+}
+
+// sockSend writes the iovecs at iovsPtr and stores the bytes sent at sentPtr.
+func sockSend(caller *wasmtime.Caller, fd, iovsPtr, iovsLen, flags, sentPtr int32) int32 {
+	state, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	bufs, ok := readIovecs(guestMemory(caller), iovsPtr, iovsLen)
+	if !ok {
+		return errnoFault
+	}
+	// The data is copied out, so guest memory isn't referenced while blocked.
+	payload := bytes.Join(bufs, nil)
+
+	if c, ok := netConn(conn); ok {
+		var timeout time.Duration
+		if sock, ok := conn.(*wasiSocket); ok {
+			timeout = sock.sendTimeout
+		}
+		c.SetWriteDeadline(deadline(state.Context(), timeout))
+	}
+	n, err := conn.Write(payload)
+	if !putUint32(guestMemory(caller), sentPtr, uint32(n)) {
+		return errnoFault
+	}
+	if err != nil {
+		return socketErrno(err)
+	}
+	return errnoSuccess
+}
+
+// sockRecv reads into the iovecs at iovsPtr and stores the bytes received at
+// recvPtr; 0 means the peer closed the connection.
+func sockRecv(caller *wasmtime.Caller, fd, iovsPtr, iovsLen, flags, recvPtr, oflagsPtr int32) int32 {
+	state, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	bufs, ok := readIovecs(guestMemory(caller), iovsPtr, iovsLen)
+	if !ok {
+		return errnoFault
+	}
+	size := 0
+	for _, buf := range bufs {
+		size += len(buf)
+	}
+	buf := make([]byte, min(size, maxRecvChunk))
+
+	if c, ok := netConn(conn); ok {
+		var timeout time.Duration
+		if sock, ok := conn.(*wasiSocket); ok {
+			timeout = sock.recvTimeout
+		}
+		c.SetReadDeadline(deadline(state.Context(), timeout))
+	}
+	var n int
+	var err error
+	if flags&recvWaitAll != 0 {
+		n, err = io.ReadFull(conn, buf)
+	} else {
+		n, err = conn.Read(buf)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+	if err != nil && n == 0 {
+		return socketErrno(err)
+	}
+
+	// Reacquire memory and scatter what was read over the iovecs.
+	data := guestMemory(caller)
+	bufs, ok = readIovecs(data, iovsPtr, iovsLen)
+	if !ok {
+		return errnoFault
+	}
+	rest := buf[:n]
+	for _, dst := range bufs {
+		rest = rest[copy(dst, rest):]
+	}
+	if !putUint32(data, recvPtr, uint32(n)) {
+		return errnoFault
+	}
+	if oflags, ok := guestSlice(data, oflagsPtr, 2); ok {
+		binary.LittleEndian.PutUint16(oflags, 0)
+	}
+	return errnoSuccess
+}
+
+// sockShutdown shuts down one or both directions of a connection. Shutting
+// down both closes the socket and frees its descriptor.
+func sockShutdown(caller *wasmtime.Caller, fd, how int32) int32 {
+	state, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	if how&(shutdownRead|shutdownWrite) == shutdownRead|shutdownWrite {
+		state.Sockets.Remove(int(fd))
+		conn.Close()
+		return errnoSuccess
+	}
+	c, ok := netConn(conn)
+	if !ok {
+		return errnoNotConn
+	}
+	tcp, ok := c.(*net.TCPConn)
+	if !ok {
+		return errnoNotSup
+	}
+	var err error
+	switch {
+	case how&shutdownRead != 0:
+		err = tcp.CloseRead()
+	case how&shutdownWrite != 0:
+		err = tcp.CloseWrite()
+	default:
+		return errnoInval
+	}
+	if err != nil {
+		return socketErrno(err)
+	}
+	return errnoSuccess
+}
+
+// sockGetPeerAddr stores the remote address of a connection.
+func sockGetPeerAddr(caller *wasmtime.Caller, fd, addrPtr, typePtr, portPtr int32) int32 {
+	_, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	c, ok := netConn(conn)
+	if !ok {
+		return errnoNotConn
+	}
+	if c.RemoteAddr() == nil {
+		return errnoNotConn
+	}
+	addrPort, err := netip.ParseAddrPort(c.RemoteAddr().String())
+	if err != nil {
+		return errnoIO
+	}
+	return writeWasiAddress(guestMemory(caller), addrPtr, typePtr, portPtr, addrPort)
+}
+
+// sockGetLocalAddr stores the local address of a socket: the connection's
+// if connected, else the unspecified address and port 0.
+func sockGetLocalAddr(caller *wasmtime.Caller, fd, addrPtr, typePtr, portPtr int32) int32 {
+	_, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	var addrPort netip.AddrPort
+	if c, ok := netConn(conn); ok {
+		var err error
+		if addrPort, err = netip.ParseAddrPort(c.LocalAddr().String()); err != nil {
+			return errnoIO
+		}
+	} else if sock, ok := conn.(*wasiSocket); ok {
+		addrPort = netip.AddrPortFrom(sock.unspecified(), 0)
+	} else {
+		return errnoNotConn
+	}
+	return writeWasiAddress(guestMemory(caller), addrPtr, typePtr, portPtr, addrPort)
+}
+
+// sockSetSockOpt sets a SOL_SOCKET option. Options that can't be honoured
+// fail with ENOPROTOOPT rather than being ignored.
+func sockSetSockOpt(caller *wasmtime.Caller, fd, level, name, valuePtr, valueLen int32) int32 {
+	_, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	sock, ok := conn.(*wasiSocket)
+	if !ok {
+		return errnoNotSock
+	}
+	if level != solSocket {
+		return errnoNoProtoOpt
+	}
+	value, ok := guestSlice(guestMemory(caller), valuePtr, valueLen)
+	if !ok {
+		return errnoFault
+	}
+
+	switch name {
+	case soRcvTimeo, soSndTimeo:
+		// struct timeval: 64-bit seconds and microseconds.
+		if len(value) < 16 {
+			return errnoInval
+		}
+		timeout := time.Duration(binary.LittleEndian.Uint64(value))*time.Second +
+			time.Duration(binary.LittleEndian.Uint64(value[8:]))*time.Microsecond
+		if name == soRcvTimeo {
+			sock.recvTimeout = timeout
+		} else {
+			sock.sendTimeout = timeout
+		}
+		return errnoSuccess
+	}
+
+	if len(value) < 4 {
+		return errnoInval
+	}
+	v := int32(binary.LittleEndian.Uint32(value))
+	tcp, _ := sock.conn.(*net.TCPConn)
+	switch name {
+	case soKeepAlive:
+		sock.keepAlive = v != 0
+		if tcp != nil {
+			tcp.SetKeepAlive(sock.keepAlive)
+		}
+	case soSndBuf:
+		sock.sendBuf = v
+		if tcp != nil {
+			tcp.SetWriteBuffer(int(v))
+		}
+	case soRcvBuf:
+		sock.recvBuf = v
+		if tcp != nil {
+			tcp.SetReadBuffer(int(v))
+		}
+	default:
+		return errnoNoProtoOpt
+	}
+	return errnoSuccess
+}
+
+// sockGetSockOpt reads a SOL_SOCKET option into the buffer at valuePtr,
+// storing its length at lenPtr.
+func sockGetSockOpt(caller *wasmtime.Caller, fd, level, name, valuePtr, lenPtr int32) int32 {
+	_, conn, errno := wasiSocketState(caller, fd)
+	if errno != errnoSuccess {
+		return errno
+	}
+	sock, ok := conn.(*wasiSocket)
+	if !ok {
+		return errnoNotSock
+	}
+	if level != solSocket {
+		return errnoNoProtoOpt
+	}
+
+	var v int32
+	switch name {
+	case soType:
+		v = int32(sockStream)
+	case soError, soAcceptConn:
+		v = 0
+	case soKeepAlive:
+		v = boolOption(sock.keepAlive)
+	case soSndBuf:
+		v = sock.sendBuf
+	case soRcvBuf:
+		v = sock.recvBuf
+	default:
+		return errnoNoProtoOpt
+	}
+	data := guestMemory(caller)
+	if !putUint32(data, valuePtr, uint32(v)) || !putUint32(data, lenPtr, 4) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
+
+func boolOption(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Layout of the structures sock_getaddrinfo fills in, for wasm32:
+//
+//	addrinfo: flags u16 | family u8 | socktype u8 | protocol u8 | pad[3] |
+//	          addrlen u32 | addr *sockaddr | canonname *u8 | canonnamelen u32 | next *addrinfo
+//	sockaddr: family u8 | pad[3] | data_len u32 | data *u8
+//
+// The sockaddr data is the port, big-endian, followed by the address bytes.
+const (
+	addrinfoSize     = 28
+	sockaddrSize     = 12
+	addrinfoFamily   = 2
+	addrinfoSockType = 3
+	addrinfoProtocol = 4
+	addrinfoAddrLen  = 8
+	addrinfoAddr     = 12
+	addrinfoCanonLen = 20
+	addrinfoNext     = 24
+	sockaddrDataLen  = 4
+	sockaddrData     = 8
+)
+
+// sockGetAddrInfo resolves a host and service. The guest passes a chain of
+// maxResults preallocated addrinfo entries, reached through the pointer at
+// resPtr; the host fills in as many as it has addresses for and stores their
+// number at resLenPtr.
+func sockGetAddrInfo(caller *wasmtime.Caller, nodePtr, nodeLen, servicePtr, serviceLen, hintsPtr, resPtr, maxResults, resLenPtr int32) int32 {
+	state, ok := caller.Data().(*State)
+	if !ok {
+		return errnoNotSup
+	}
+	data := guestMemory(caller)
+	nodeBytes, ok := guestSlice(data, nodePtr, nodeLen)
+	if !ok {
+		return errnoFault
+	}
+	serviceBytes, ok := guestSlice(data, servicePtr, serviceLen)
+	if !ok {
+		return errnoFault
+	}
+	node := string(bytes.TrimRight(nodeBytes, "\x00"))
+	service := string(bytes.TrimRight(serviceBytes, "\x00"))
+
+	family, socketType := afUnspec, sockAny
+	if hints, ok := guestSlice(data, hintsPtr, addrinfoSize); ok && hintsPtr != 0 {
+		family, socketType = hints[addrinfoFamily], hints[addrinfoSockType]
+	}
+	if family != afUnspec && family != afInet4 && family != afInet6 {
+		return errnoAIAddrFamily
+	}
+	if socketType != sockAny && socketType != sockStream {
+		return errnoAISockType
+	}
+
+	port := 0
+	if service != "" {
+		var err error
+		if port, err = strconv.Atoi(service); err != nil {
+			if port, err = net.LookupPort("tcp", service); err != nil {
+				return errnoAIService
+			}
+		}
+	}
+
+	var addrs []netip.Addr
+	if node == "" {
+		addrs = []netip.Addr{netip.IPv4Unspecified()}
+	} else if addr, err := netip.ParseAddr(node); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		resolved, err := state.Network.Egress.LookupHost(state.Context(), "ip", node)
+		var dnsErr *net.DNSError
+		if state.egressDenied("sock_getaddrinfo", err) || errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return errnoAINoName
+		}
+		if err != nil {
+			return errnoAIFail
+		}
+		addrs = resolved
+	}
+
+	// Memory is reacquired after resolution, which may take a while.
+	data = guestMemory(caller)
+	entry, ok := guestSlice(data, resPtr, 4)
+	if !ok {
+		return errnoFault
+	}
+	next := int32(binary.LittleEndian.Uint32(entry))
+	count := int32(0)
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		addrFamily := afInet4
+		if addr.Is6() {
+			addrFamily = afInet6
+		}
+		if family != afUnspec && family != addrFamily {
+			continue
+		}
+		if count >= maxResults || next == 0 {
+			break
+		}
+		info, ok := guestSlice(data, next, addrinfoSize)
+		if !ok {
+			return errnoFault
+		}
+		sockaddr, ok := guestSlice(data, int32(binary.LittleEndian.Uint32(info[addrinfoAddr:])), sockaddrSize)
+		if !ok {
+			return errnoFault
+		}
+		raw := addr.AsSlice()
+		sockData, ok := guestSlice(data, int32(binary.LittleEndian.Uint32(sockaddr[sockaddrData:])), int32(binary.LittleEndian.Uint32(sockaddr[sockaddrDataLen:])))
+		if !ok {
+			return errnoFault
+		}
+		if len(sockData) < 2+len(raw) {
+			continue
+		}
+		binary.BigEndian.PutUint16(sockData, uint16(port))
+		copy(sockData[2:], raw)
+		sockaddr[0] = addrFamily
+		binary.LittleEndian.PutUint32(sockaddr[sockaddrDataLen:], uint32(2+len(raw)))
+
+		info[addrinfoFamily] = addrFamily
+		info[addrinfoSockType] = sockStream
+		info[addrinfoProtocol] = protoTCP
+		binary.LittleEndian.PutUint32(info[addrinfoAddrLen:], sockaddrSize)
+		binary.LittleEndian.PutUint32(info[addrinfoCanonLen:], 0)
+		count++
+		next = int32(binary.LittleEndian.Uint32(info[addrinfoNext:]))
+	}
+	if count == 0 {
+		return errnoAINoName
+	}
+	if !putUint32(data, resLenPtr, uint32(count)) {
+		return errnoFault
+	}
+	return errnoSuccess
+}
@@ -0,0 +1,173 @@
+package host_functions_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/google/uuid"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+)
+
+// buildCClient compiles testdata/sockclient/sockclient.c with a WASI clang,
+// from WASI_SDK_PATH if set, skipping the test when none is available.
+func buildCClient(t *testing.T) []byte {
+	t.Helper()
+	clang := "clang"
+	if sdk := os.Getenv("WASI_SDK_PATH"); sdk != "" {
+		clang = filepath.Join(sdk, "bin", "clang")
+	}
+	out := filepath.Join(t.TempDir(), "sockclient.wasm")
+	cmd := exec.Command(clang, "--target=wasm32-wasip1", "-O2", "-o", out, filepath.Join("testdata", "sockclient", "sockclient.c"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("building sockclient.c: %v\n%s", err, output)
+	}
+	return readModule(t, out)
+}
+
+// buildGoClient compiles testdata/sockclient for wasip1.
+func buildGoClient(t *testing.T) []byte {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "sockclient.wasm")
+	cmd := exec.Command("go", "build", "-o", out, "./testdata/sockclient")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("building testdata/sockclient: %v\n%s", err, output)
+	}
+	return readModule(t, out)
+}
+
+func readModule(t *testing.T, path string) []byte {
+	t.Helper()
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wasm
+}
+
+// echoServer accepts connections on loopback and echoes the first line of
+// each, returning the port it listens on.
+func echoServer(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 256)
+				n, _ := bufio.NewReader(conn).Read(buf)
+				conn.Write(buf[:n])
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// prepareClient compiles the socket client module and links it against the
+// host functions.
+func prepareClient(t *testing.T, wasm []byte) *runtime.InstancePre {
+	t.Helper()
+	engine := runtime.NewEngine(runtime.PoolingConfig{})
+	runtime.StartEpochTicker(engine)
+	linker, err := runtime.NewLinker(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	module, err := wasmtime.NewModule(engine, wasm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre, err := runtime.NewInstancePre(engine, linker, module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pre
+}
+
+// runClient runs the socket client once and returns its stdout.
+func runClient(t *testing.T, pre *runtime.InstancePre, network *host_functions.Network, args ...string) (string, error) {
+	t.Helper()
+	session := runtime.Session{
+		ID:      uuid.New(),
+		Args:    append([]string{"sockclient"}, args...),
+		Pre:     pre,
+		Network: network,
+		Timeout: 10 * time.Second,
+	}
+	defer session.Close()
+	var stdout, stderr bytes.Buffer
+	store, err := session.NewStore(nil, &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.Run(context.Background(), store)
+	store.Close()
+	if stderr.Len() > 0 {
+		t.Logf("stderr: %s", stderr.String())
+	}
+	return stdout.String(), err
+}
+
+func TestWasiSocketClient(t *testing.T) {
+	clients := []struct {
+		name  string
+		build func(*testing.T) []byte
+	}{
+		{"c", buildCClient},
+		{"go", buildGoClient},
+	}
+	port := strconv.Itoa(echoServer(t))
+
+	for _, client := range clients {
+		t.Run(client.name, func(t *testing.T) {
+			pre := prepareClient(t, client.build(t))
+
+			t.Run("allowed", func(t *testing.T) {
+				egress, err := host_functions.NewEgressPolicy(nil, []string{"127.0.0.0/8"}, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				network := host_functions.NewNetwork(egress, host_functions.DefaultHTTPLimits)
+				out, err := runClient(t, pre, network, "localhost", port, "hello\n")
+				if err != nil {
+					t.Fatalf("run: %v\n%s", err, out)
+				}
+				for _, want := range []string{"resolved 127.0.0.1:" + port, "peer port " + port, "echo: hello"} {
+					if !strings.Contains(out, want) {
+						t.Errorf("output lacks %q:\n%s", want, out)
+					}
+				}
+			})
+
+			t.Run("denied by egress policy", func(t *testing.T) {
+				out, err := runClient(t, pre, nil, "localhost", port, "hello\n")
+				if err == nil {
+					t.Fatalf("run: want a non-zero exit\n%s", out)
+				}
+				if !strings.Contains(out, "connect: errno 2") { // EACCES
+					t.Errorf("output lacks the refused connect:\n%s", out)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package runtime
+
+// The Go bindings don't expose Wasmtime's InstancePre, which resolves a
+// module's imports once so each instantiation only copies them into the
+// store. libwasmtime does; the prototypes below mirror linker.h and
+// instance.h.
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+
+typedef struct wasmtime_linker wasmtime_linker_t;
+typedef struct wasmtime_module wasmtime_module_t;
+typedef struct wasmtime_store wasmtime_store_t;
+typedef struct wasmtime_context wasmtime_context_t;
+typedef struct wasmtime_error wasmtime_error_t;
+typedef struct wasm_trap_t wasm_trap_t;
+typedef struct wasmtime_instance_pre wasmtime_instance_pre_t;
+typedef struct wasmtime_instance {
+	uint64_t store_id;
+	size_t __private;
+} wasmtime_instance_t;
+typedef struct wasm_byte_vec_t {
+	size_t size;
+	char *data;
+} wasm_byte_vec_t;
+
+extern wasmtime_context_t *wasmtime_store_context(wasmtime_store_t *store);
+extern wasmtime_error_t *wasmtime_linker_instantiate_pre(const wasmtime_linker_t *linker,
+	const wasmtime_module_t *module, wasmtime_instance_pre_t **instance_pre);
+extern wasmtime_error_t *wasmtime_instance_pre_instantiate(const wasmtime_instance_pre_t *instance_pre,
+	wasmtime_context_t *store, wasmtime_instance_t *instance, wasm_trap_t **trap_ptr);
+extern void wasmtime_instance_pre_delete(wasmtime_instance_pre_t *instance_pre);
+extern void wasmtime_error_message(const wasmtime_error_t *error, wasm_byte_vec_t *message);
+extern void wasmtime_error_delete(wasmtime_error_t *error);
+extern void wasm_trap_message(const wasm_trap_t *trap, wasm_byte_vec_t *out);
+extern void wasm_trap_delete(wasm_trap_t *trap);
+extern void wasm_byte_vec_delete(wasm_byte_vec_t *vec);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	goruntime "runtime"
+	"unsafe"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+)
+
+// NewLinker builds the linker shared by every execution on the engine, with
+// WASI and all host functions defined. Nothing in it is tied to a store.
+func NewLinker(engine *wasmtime.Engine) (*wasmtime.Linker, error) {
+	linker := wasmtime.NewLinker(engine)
+
+	if err := linker.DefineWasi(); err != nil {
+		return nil, fmt.Errorf("wasi link: %w", err)
+	}
+
+	if err := host_functions.Link(linker); err != nil {
+		return nil, fmt.Errorf("host functions link: %w", err)
+	}
+
+	return linker, nil
+}
+
+// InstancePre is a compiled module pre-linked against a shared linker. Its
+// imports are resolved once when it is created, so each execution only pays
+// for store creation and instantiation.
+type InstancePre struct {
+	engine *wasmtime.Engine
+	module *wasmtime.Module
+	ptr    *C.wasmtime_instance_pre_t
+}
+
+// NewInstancePre resolves every import of module against linker. It fails if
+// the linker doesn't provide one.
+func NewInstancePre(engine *wasmtime.Engine, linker *wasmtime.Linker, module *wasmtime.Module) (*InstancePre, error) {
+	var ptr *C.wasmtime_instance_pre_t
+	err := C.wasmtime_linker_instantiate_pre(linkerPtr(linker), modulePtr(module), &ptr)
+	goruntime.KeepAlive(linker)
+	goruntime.KeepAlive(module)
+	if err != nil {
+		return nil, errorFromC(err)
+	}
+
+	p := &InstancePre{engine: engine, module: module, ptr: ptr}
+	goruntime.SetFinalizer(p, func(p *InstancePre) {
+		C.wasmtime_instance_pre_delete(p.ptr)
+	})
+	return p, nil
+}
+
+// Engine returns the engine the module was compiled for.
+func (p *InstancePre) Engine() *wasmtime.Engine {
+	return p.engine
+}
+
+// Module returns the compiled module.
+func (p *InstancePre) Module() *wasmtime.Module {
+	return p.module
+}
+
+// Instantiate creates a new instance of the module inside store. A trap in
+// the module's start function is returned as an error carrying the trap's
+// message.
+func (p *InstancePre) Instantiate(store *wasmtime.Store) (*wasmtime.Instance, error) {
+	var instance C.wasmtime_instance_t
+	var trap *C.wasm_trap_t
+	err := C.wasmtime_instance_pre_instantiate(p.ptr, C.wasmtime_store_context(storePtr(store)), &instance, &trap)
+	goruntime.KeepAlive(p)
+	goruntime.KeepAlive(store)
+	switch {
+	case err != nil:
+		return nil, errorFromC(err)
+	case trap != nil:
+		defer C.wasm_trap_delete(trap)
+		var message C.wasm_byte_vec_t
+		C.wasm_trap_message(trap, &message)
+		defer C.wasm_byte_vec_delete(&message)
+		// The trap message is NUL-terminated.
+		return nil, errors.New(C.GoStringN(message.data, C.int(message.size-1)))
+	}
+
+	// wasmtime.Instance is a struct holding only the wasmtime_instance_t.
+	ret := new(wasmtime.Instance)
+	*(*C.wasmtime_instance_t)(unsafe.Pointer(ret)) = instance
+	return ret, nil
+}
+
+// errorFromC converts and frees a wasmtime_error_t.
+func errorFromC(err *C.wasmtime_error_t) error {
+	defer C.wasmtime_error_delete(err)
+	var message C.wasm_byte_vec_t
+	C.wasmtime_error_message(err, &message)
+	defer C.wasm_byte_vec_delete(&message)
+	return errors.New(C.GoStringN(message.data, C.int(message.size)))
+}
+
+// linkerPtr returns the wasmtime_linker_t behind linker. wasmtime.Linker
+// starts with that pointer; this breaks if the bindings change its layout.
+func linkerPtr(linker *wasmtime.Linker) *C.wasmtime_linker_t {
+	return (*struct{ ptr *C.wasmtime_linker_t })(unsafe.Pointer(linker)).ptr
+}
+
+// modulePtr returns the wasmtime_module_t behind module. wasmtime.Module is a
+// struct holding only that pointer; this breaks if the bindings change its
+// layout.
+func modulePtr(module *wasmtime.Module) *C.wasmtime_module_t {
+	return (*struct{ ptr *C.wasmtime_module_t })(unsafe.Pointer(module)).ptr
+}
@@ -1,19 +1,20 @@
 package js
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
+	"time"
 
-	"github.com/bytecodealliance/wasmtime-go/v41"
 	"github.com/cespare/xxhash/v2"
 	"github.com/google/uuid"
 
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/models"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
 )
 
 //go:embed qjs.wasm
@@ -25,16 +26,31 @@ const (
 
 // RuntimeJS implements the Runtime interface for JavaScript execution using QuickJS
 type RuntimeJS struct {
-	session runtime.Session
+	session     runtime.Session
+	maxLogBytes int
+	stats       runtime.ExecutionStats
+	logs        runtime.ExecutionLog
 }
 
 // runtimeConfig handles the configuration for JS execution
 type runtimeConfig struct {
-	id               uuid.UUID
-	jsFile           []byte // The JavaScript source code
-	serializedModule []byte // Pre-compiled QuickJS .cwasm bytes
-	err              error
-	hash             string
+	id          uuid.UUID
+	jsFile      []byte               // The JavaScript source code
+	pre         *runtime.InstancePre // Pre-linked QuickJS engine
+	env         map[string]string
+	workspace   *runtime.Workspace
+	network     *host_functions.Network
+	kv          kv.Store
+	secrets     host_functions.SecretStore
+	logLimit    host_functions.LogLimit
+	metrics     host_functions.MetricSink
+	invoker     host_functions.Invoker
+	timeout     time.Duration
+	fuelBudget  uint64
+	limits      runtime.ResourceLimits
+	maxLogBytes int
+	err         error
+	hash        string
 }
 
 // NewRuntimeConfig initializes a new builder with the required ID
@@ -52,9 +68,86 @@ func (b *runtimeConfig) WithJSFile(jsFile []byte) *runtimeConfig {
 	return b
 }
 
-// WithSerializedModule provides the pre-compiled QuickJS engine bytes
-func (b *runtimeConfig) WithSerializedModule(data []byte) *runtimeConfig {
-	b.serializedModule = data
+// WithInstancePre provides the pre-linked QuickJS module. It is shared across
+// executions and outlives the runtime.
+func (b *runtimeConfig) WithInstancePre(pre *runtime.InstancePre) *runtimeConfig {
+	b.pre = pre
+	return b
+}
+
+// WithTimeout bounds the wall-clock time of each script execution
+func (b *runtimeConfig) WithTimeout(timeout time.Duration) *runtimeConfig {
+	b.timeout = timeout
+	return b
+}
+
+// WithFuelBudget sets the amount of fuel each script execution may consume
+func (b *runtimeConfig) WithFuelBudget(fuel uint64) *runtimeConfig {
+	b.fuelBudget = fuel
+	return b
+}
+
+// WithLimits caps the memory, tables and instances each script execution may allocate
+func (b *runtimeConfig) WithLimits(limits runtime.ResourceLimits) *runtimeConfig {
+	b.limits = limits
+	return b
+}
+
+// WithEnv sets the environment variables visible to the guest. The host
+// environment is never inherited.
+func (b *runtimeConfig) WithEnv(env map[string]string) *runtimeConfig {
+	b.env = env
+	return b
+}
+
+// WithMaxLogBytes caps the stderr kept from each script execution
+func (b *runtimeConfig) WithMaxLogBytes(max int) *runtimeConfig {
+	b.maxLogBytes = max
+	return b
+}
+
+// WithWorkspace mounts the deployment's private workspace at
+// runtime.WorkspaceGuestPath, next to the read-only modules
+func (b *runtimeConfig) WithWorkspace(ws *runtime.Workspace) *runtimeConfig {
+	b.workspace = ws
+	return b
+}
+
+// WithNetwork sets the deployment's egress policy and shared HTTP client,
+// used when the script connects out through host functions. Without it only
+// public addresses are reachable
+func (b *runtimeConfig) WithNetwork(network *host_functions.Network) *runtimeConfig {
+	b.network = network
+	return b
+}
+
+// WithKV sets the store behind the script's host_kv functions
+func (b *runtimeConfig) WithKV(kv kv.Store) *runtimeConfig {
+	b.kv = kv
+	return b
+}
+
+// WithSecrets sets the store behind the script's host_secret_get
+func (b *runtimeConfig) WithSecrets(secrets host_functions.SecretStore) *runtimeConfig {
+	b.secrets = secrets
+	return b
+}
+
+// WithMetrics sets the sink aggregating the script's host_metric samples
+func (b *runtimeConfig) WithMetrics(metrics host_functions.MetricSink) *runtimeConfig {
+	b.metrics = metrics
+	return b
+}
+
+// WithInvoker sets what runs the deployments the script calls with host_invoke
+func (b *runtimeConfig) WithInvoker(invoker host_functions.Invoker) *runtimeConfig {
+	b.invoker = invoker
+	return b
+}
+
+// WithLogLimit bounds the records the script emits with host_log
+func (b *runtimeConfig) WithLogLimit(limit host_functions.LogLimit) *runtimeConfig {
+	b.logLimit = limit
 	return b
 }
 
@@ -79,21 +172,8 @@ func (b *runtimeConfig) Instantiate() (runtime.Runtime, error) {
 		return nil, fmt.Errorf("no javascript source provided")
 	}
 
-	engine := wasmtime.NewEngine()
-	var module *wasmtime.Module
-	var err error
-
-	// 1. Attempt to use Serialized Module (QuickJS Engine Cache Hit)
-	if len(b.serializedModule) > 0 {
-		module, err = wasmtime.NewModuleDeserialize(engine, b.serializedModule)
-		if err != nil {
-			module = nil // Fallback to raw if deserialization fails
-		}
-	}
-
-	stdin, stdout, err := runtime.CreateIoDescriptors(b.id)
-	if err != nil {
-		return nil, fmt.Errorf("io setup failed: %w", err)
+	if b.pre == nil {
+		return nil, fmt.Errorf("no compiled QuickJS module provided")
 	}
 
 	// Prepare the command line arguments for QuickJS: ["qjs", "-e", "<script_content>"]
@@ -101,14 +181,24 @@ func (b *runtimeConfig) Instantiate() (runtime.Runtime, error) {
 
 	return &RuntimeJS{
 		session: runtime.Session{
-			ID:           b.id,
-			Args:         args,
-			Engine:       engine,
-			Module:       module,
-			Stdin:        stdin,
-			Stdout:       stdout,
-			PreOpenedDir: defaultModulesDir,
+			ID:   b.id,
+			Env:  b.env,
+			Args: args,
+			Pre:  b.pre,
+			// The modules are shared by every deployment and never writable
+			Mounts:     []runtime.Mount{runtime.ReadOnlyMount(defaultModulesDir, "/")},
+			Workspace:  b.workspace,
+			Network:    b.network,
+			KV:         b.kv,
+			Secrets:    b.secrets,
+			LogLimit:   b.logLimit,
+			Metrics:    b.metrics,
+			Invoker:    b.invoker,
+			Timeout:    b.timeout,
+			FuelBudget: b.fuelBudget,
+			Limits:     b.limits,
 		},
+		maxLogBytes: b.maxLogBytes,
 	}, nil
 }
 
@@ -119,45 +209,42 @@ func (r *RuntimeJS) Execute(ctx context.Context, fdRequest any) ([]byte, error)
 		return nil, fmt.Errorf("expected []byte for JavaScript input")
 	}
 
-	// Reset Stdin for fresh execution
-	if err := r.resetFile(r.session.Stdin); err != nil {
-		return nil, err
-	}
-
-	if _, err := r.session.Stdin.Write(reqBytes); err != nil {
-		return nil, fmt.Errorf("failed to write to JS stdin: %w", err)
-	}
-	_ = r.session.Stdin.Sync()
-
-	// Setup Store & Linker
-	store, linker, err := r.session.NewStore()
+	// Setup Store with the request as stdin
+	var stdout bytes.Buffer
+	stderr := runtime.NewLogBuffer(r.maxLogBytes)
+	store, err := r.session.NewStore(reqBytes, &stdout, stderr)
 	if err != nil {
 		return nil, err
 	}
-	defer store.Close()
 
 	// Run QuickJS
-	if err := r.session.Run(store, linker); err != nil {
+	err = r.session.Run(ctx, store)
+	r.stats = r.session.Stats(store)
+
+	// Closing the store releases the guest's stdio, so the buffers are final
+	store.Close()
+	r.logs = stderr.Log()
+	r.logs.Records, r.logs.RecordsDropped = r.session.LogRecords()
+	if err != nil {
 		return nil, fmt.Errorf("JS runtime error: %w", err)
 	}
 
-	// Read Output from deterministic stdout
-	if _, err := r.session.Stdout.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("failed to seek stdout: %w", err)
-	}
+	return stdout.Bytes(), nil
+}
 
-	return io.ReadAll(r.session.Stdout)
+// Stats reports resource usage of the most recent script execution
+func (r *RuntimeJS) Stats() runtime.ExecutionStats {
+	return r.stats
 }
 
-func (r *RuntimeJS) resetFile(f *os.File) error {
-	if _, err := f.Seek(0, io.SeekStart); err != nil {
-		return err
-	}
-	return f.Truncate(0)
+// Logs returns the stderr and host_log records captured during the most
+// recent script execution
+func (r *RuntimeJS) Logs() runtime.ExecutionLog {
+	return r.logs
 }
 
-// Close cleans up the /dev/shm files
+// Close releases the runtime, closing any connections the script left open.
+// Stdio lives in memory and is dropped with each execution's store.
 func (r *RuntimeJS) Close(ctx context.Context) error {
-	r.session.Cleanup()
-	return nil
+	return r.session.Close()
 }
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
+)
+
+// Resources reported by LimitError and the resource limit metric.
+const (
+	ResourceMemory        = "memory"
+	ResourceTableElements = "table_elements"
+	ResourceInstances     = "instances"
+	ResourceMemories      = "memories"
+	ResourceWallTime      = "wall_time"
+	ResourceFuel          = "fuel"
+	ResourceDisk          = "disk"
+)
+
+var limitExceeded = metrics.NewCounterVec(
+	"ignis_resource_limit_exceeded_total",
+	"Executions stopped because a guest exceeded one of its resource limits.",
+	"deployment_id", "resource",
+)
+
+// ResourceLimits caps what a single store may allocate. Zero values keep
+// Wasmtime's defaults.
+type ResourceLimits struct {
+	MaxMemoryBytes   int64
+	MaxTableElements int64
+	MaxInstances     int64
+	MaxMemories      int64
+}
+
+// apply installs the limits on the store's resource limiter.
+func (l ResourceLimits) apply(store *wasmtime.Store) {
+	store.Limiter(
+		limitOrDefault(l.MaxMemoryBytes),
+		limitOrDefault(l.MaxTableElements),
+		limitOrDefault(l.MaxInstances),
+		-1,
+		limitOrDefault(l.MaxMemories),
+	)
+}
+
+func limitOrDefault(v int64) int64 {
+	if v <= 0 {
+		return -1
+	}
+	return v
+}
+
+// LimitError reports that a guest was stopped by one of its resource limits.
+type LimitError struct {
+	Resource string
+	Limit    int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("resource limit exceeded: %s (limit %d)", e.Resource, e.Limit)
+}
+
+// classify turns a refusal by the store limiter into a *LimitError.
+//
+// Only refusals Wasmtime reports as errors are recognised: those hit while
+// instantiating, such as an initial memory or table above its limit. A
+// memory.grow or table.grow the limiter refuses while the guest runs just
+// returns -1 to the guest, and the store limiter offers no way to observe it,
+// so whatever the guest does next is reported as its own failure rather than
+// guessed at from the size of its memory.
+func (l ResourceLimits) classify(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "exceeds memory limits"):
+		return &LimitError{Resource: ResourceMemory, Limit: l.MaxMemoryBytes}
+	case strings.Contains(msg, "exceeds table limits"):
+		return &LimitError{Resource: ResourceTableElements, Limit: l.MaxTableElements}
+	case strings.Contains(msg, "instance count too high"):
+		return &LimitError{Resource: ResourceInstances, Limit: l.MaxInstances}
+	case strings.Contains(msg, "memory count too high"):
+		return &LimitError{Resource: ResourceMemories, Limit: l.MaxMemories}
+	}
+	return err
+}
+
+// recordLimitExceeded counts executions stopped by a resource control.
+func recordLimitExceeded(id uuid.UUID, err error) {
+	var limitErr *LimitError
+	switch {
+	case errors.As(err, &limitErr):
+		limitExceeded.Inc(id.String(), limitErr.Resource)
+	case errors.Is(err, ErrExecutionTimeout):
+		limitExceeded.Inc(id.String(), ResourceWallTime)
+	case errors.Is(err, ErrFuelExhausted):
+		limitExceeded.Inc(id.String(), ResourceFuel)
+	}
+}
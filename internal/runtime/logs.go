@@ -0,0 +1,66 @@
+package runtime
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+)
+
+// DefaultMaxLogBytes caps the guest stderr kept from a single execution when
+// the runtime is not configured with its own limit.
+const DefaultMaxLogBytes = 64 << 10
+
+// ExecutionLog is the guest stderr and the host_log records captured during
+// one execution.
+type ExecutionLog struct {
+	Stderr []byte
+	// Truncated reports that the guest wrote more than the cap and the
+	// remainder was dropped.
+	Truncated bool
+	Records   []host_functions.LogRecord
+	// RecordsDropped counts the records left out by the rate limit or the
+	// retention cap.
+	RecordsDropped int
+}
+
+// LogBuffer collects guest output up to a fixed size. Writes past the cap are
+// discarded but still reported as successful, so a chatty guest isn't failed
+// for logging too much. It is safe for concurrent use.
+type LogBuffer struct {
+	mu        sync.Mutex
+	max       int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// NewLogBuffer creates a buffer keeping at most max bytes, or
+// DefaultMaxLogBytes when max is not positive.
+func NewLogBuffer(max int) *LogBuffer {
+	if max <= 0 {
+		max = DefaultMaxLogBytes
+	}
+	return &LogBuffer{max: max}
+}
+
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// Log returns what has been captured so far.
+func (b *LogBuffer) Log() ExecutionLog {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return ExecutionLog{
+		Stderr:    bytes.Clone(b.buf.Bytes()),
+		Truncated: b.truncated,
+	}
+}
@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"container/list"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
+)
+
+var (
+	moduleCacheHits = metrics.NewCounterVec(
+		"ignis_module_cache_hits_total",
+		"Compiled module lookups served from the in-process cache.",
+	)
+	moduleCacheMisses = metrics.NewCounterVec(
+		"ignis_module_cache_misses_total",
+		"Compiled module lookups that had to load the module.",
+	)
+	moduleCacheEvictions = metrics.NewCounterVec(
+		"ignis_module_cache_evictions_total",
+		"Compiled modules evicted from the in-process cache.",
+	)
+	moduleCacheBytes = metrics.NewGaugeVec(
+		"ignis_module_cache_bytes",
+		"Estimated size of the compiled modules held in the in-process cache.",
+	)
+)
+
+// ModuleLoader produces a pre-linked module along with its estimated size in bytes.
+type ModuleLoader func() (*InstancePre, int64, error)
+
+// ModuleCache is an in-process LRU of pre-linked compiled modules bounded by
+// their estimated size. It is safe for concurrent use.
+//
+// Evicted modules are not closed explicitly: executions may still hold them,
+// so they are released by their finalizer once unreachable.
+type ModuleCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+	// loads lets concurrent misses for a key share a single load.
+	loads singleflight.Group
+}
+
+type moduleCacheEntry struct {
+	key  string
+	pre  *InstancePre
+	size int64
+}
+
+// NewModuleCache creates a cache holding at most maxBytes of compiled modules.
+func NewModuleCache(maxBytes int64) *ModuleCache {
+	return &ModuleCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// GetOrLoad returns the module cached under key, calling load on a miss.
+// Concurrent misses for the same key wait for a single call to load and share
+// its result, so a cold module is fetched and compiled once.
+func (c *ModuleCache) GetOrLoad(key string, load ModuleLoader) (*InstancePre, error) {
+	if pre, ok := c.get(key); ok {
+		moduleCacheHits.Inc()
+		return pre, nil
+	}
+	moduleCacheMisses.Inc()
+
+	v, err, _ := c.loads.Do(key, func() (any, error) {
+		// Another load may have finished since the lookup above
+		if pre, ok := c.get(key); ok {
+			return pre, nil
+		}
+		pre, size, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.add(key, pre, size)
+		return pre, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*InstancePre), nil
+}
+
+func (c *ModuleCache) get(key string) (*InstancePre, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*moduleCacheEntry).pre, true
+}
+
+func (c *ModuleCache) add(key string, pre *InstancePre, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+	// A module larger than the whole cache is served but never retained.
+	if size > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&moduleCacheEntry{key: key, pre: pre, size: size})
+	c.size += size
+	for c.size > c.maxBytes {
+		c.removeElement(c.order.Back())
+		moduleCacheEvictions.Inc()
+	}
+	moduleCacheBytes.Set(float64(c.size))
+}
+
+func (c *ModuleCache) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*moduleCacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	moduleCacheBytes.Set(float64(c.size))
+}
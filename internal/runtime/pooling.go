@@ -0,0 +1,92 @@
+package runtime
+
+// The Go bindings don't expose the pooling allocator, but libwasmtime, which
+// they link statically, does. The prototypes below mirror wasmtime/config.h.
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+
+typedef struct wasm_config_t wasm_config_t;
+typedef struct wasmtime_pooling_allocation_config_t wasmtime_pooling_allocation_config_t;
+
+extern wasmtime_pooling_allocation_config_t *wasmtime_pooling_allocation_config_new(void);
+extern void wasmtime_pooling_allocation_config_delete(wasmtime_pooling_allocation_config_t *);
+extern void wasmtime_pooling_allocation_config_total_core_instances_set(wasmtime_pooling_allocation_config_t *, uint32_t);
+extern void wasmtime_pooling_allocation_config_total_memories_set(wasmtime_pooling_allocation_config_t *, uint32_t);
+extern void wasmtime_pooling_allocation_config_total_tables_set(wasmtime_pooling_allocation_config_t *, uint32_t);
+extern void wasmtime_pooling_allocation_config_max_memory_size_set(wasmtime_pooling_allocation_config_t *, size_t);
+extern void wasmtime_pooling_allocation_config_table_elements_set(wasmtime_pooling_allocation_config_t *, size_t);
+extern void wasmtime_pooling_allocation_strategy_set(wasm_config_t *, const wasmtime_pooling_allocation_config_t *);
+*/
+import "C"
+
+import (
+	"errors"
+	"strings"
+	"unsafe"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+// wasmPageSize is the size of a WebAssembly linear memory page.
+const wasmPageSize = 64 << 10
+
+// ErrInstancePoolExhausted is returned when every slot of the pooling
+// allocator is taken by concurrent executions.
+var ErrInstancePoolExhausted = errors.New("instance pool exhausted")
+
+// poolExhaustedMessage is how Wasmtime reports that a pool has no free slot.
+const poolExhaustedMessage = "maximum concurrent limit of"
+
+// PoolingConfig configures Wasmtime's pooling instance allocator. The pool
+// reserves every slot up front, so creating an instance only takes a slot
+// instead of mapping fresh memory. Zero values keep Wasmtime's defaults.
+type PoolingConfig struct {
+	Enabled bool
+
+	// MaxInstances is the number of instances, and of their memories and
+	// tables, that may be live at once across the engine.
+	MaxInstances uint32
+
+	// MemoryPages is the maximum size of each linear memory in 64KiB pages.
+	MemoryPages uint64
+
+	// TableElements is the maximum number of elements in each table.
+	TableElements uint64
+}
+
+// apply switches cfg to the pooling allocation strategy.
+func (p PoolingConfig) apply(cfg *wasmtime.Config) {
+	pool := C.wasmtime_pooling_allocation_config_new()
+	defer C.wasmtime_pooling_allocation_config_delete(pool)
+
+	if p.MaxInstances > 0 {
+		C.wasmtime_pooling_allocation_config_total_core_instances_set(pool, C.uint32_t(p.MaxInstances))
+		C.wasmtime_pooling_allocation_config_total_memories_set(pool, C.uint32_t(p.MaxInstances))
+		C.wasmtime_pooling_allocation_config_total_tables_set(pool, C.uint32_t(p.MaxInstances))
+	}
+	if p.MemoryPages > 0 {
+		C.wasmtime_pooling_allocation_config_max_memory_size_set(pool, C.size_t(p.MemoryPages*wasmPageSize))
+	}
+	if p.TableElements > 0 {
+		C.wasmtime_pooling_allocation_config_table_elements_set(pool, C.size_t(p.TableElements))
+	}
+
+	C.wasmtime_pooling_allocation_strategy_set(configPtr(cfg), pool)
+}
+
+// configPtr returns the wasm_config_t behind cfg. wasmtime.Config is a struct
+// holding only that pointer; this breaks if the bindings change its layout.
+func configPtr(cfg *wasmtime.Config) *C.wasm_config_t {
+	return (*struct{ ptr *C.wasm_config_t })(unsafe.Pointer(cfg)).ptr
+}
+
+// classifyPoolExhausted maps the pooling allocator running out of slots to
+// ErrInstancePoolExhausted. Any other error is returned unchanged.
+func classifyPoolExhausted(err error) error {
+	if strings.Contains(err.Error(), poolExhaustedMessage) {
+		return ErrInstancePoolExhausted
+	}
+	return err
+}
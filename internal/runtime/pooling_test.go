@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+// exampleModules are the Go examples built for the benchmark, relative to the
+// repository root.
+var exampleModules = []string{
+	"example/go/index-page",
+	"example/go/i2",
+}
+
+// buildExample compiles an example module for wasip1, skipping the benchmark
+// when the toolchain cannot build it.
+func buildExample(b *testing.B, pkg string) []byte {
+	b.Helper()
+	out := filepath.Join(b.TempDir(), "module.wasm")
+	cmd := exec.Command("go", "build", "-o", out, "./"+pkg)
+	cmd.Dir = filepath.Join("..", "..")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		b.Skipf("building %s: %v\n%s", pkg, err, output)
+	}
+	wasm, err := os.ReadFile(out)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return wasm
+}
+
+// BenchmarkInstantiate measures instance churn for the example modules with
+// the on-demand and the pooling allocator. Each iteration creates a store,
+// instantiates the module and tears both down, which is the per-request cost
+// left once modules are compiled and pre-linked.
+func BenchmarkInstantiate(b *testing.B) {
+	strategies := []struct {
+		name    string
+		pooling PoolingConfig
+	}{
+		{"on-demand", PoolingConfig{}},
+		{"pooling", PoolingConfig{Enabled: true, MaxInstances: 1000, MemoryPages: 4096, TableElements: 65536}},
+	}
+
+	for _, pkg := range exampleModules {
+		wasm := buildExample(b, pkg)
+		for _, strategy := range strategies {
+			b.Run(filepath.Base(pkg)+"/"+strategy.name, func(b *testing.B) {
+				engine := NewEngine(strategy.pooling)
+				linker, err := NewLinker(engine)
+				if err != nil {
+					b.Fatal(err)
+				}
+				module, err := wasmtime.NewModule(engine, wasm)
+				if err != nil {
+					b.Fatal(err)
+				}
+				pre, err := NewInstancePre(engine, linker, module)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						store := wasmtime.NewStore(engine)
+						store.SetWasi(wasmtime.NewWasiConfig())
+						if _, err := pre.Instantiate(store); err != nil {
+							b.Error(err)
+						}
+						store.Close()
+					}
+				})
+			})
+		}
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/models"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
 
@@ -15,6 +20,11 @@ import (
 
 type Runtime interface {
 	Execute(ctx context.Context, fdrequest any) ([]byte, error)
+	// Stats reports resource usage of the most recent execution.
+	Stats() ExecutionStats
+	// Logs returns the guest stderr and host_log records captured during the
+	// most recent execution.
+	Logs() ExecutionLog
 	Close(ctx context.Context) error
 }
 type RuntimeConfig interface {
@@ -22,71 +32,149 @@ type RuntimeConfig interface {
 	Instantiate() (Runtime, error)
 }
 
+// ExecutionStats describes the resources consumed by one execution.
+type ExecutionStats struct {
+	FuelBudget   uint64
+	FuelConsumed uint64
+}
+
 // Session represents a single execution context.
 type Session struct {
-	ID           uuid.UUID
-	Args         []string
-	Engine       *wasmtime.Engine
-	Module       *wasmtime.Module
-	Stdin        *os.File
-	Stdout       *os.File
-	PreOpenedDir string
+	ID         uuid.UUID
+	Args       []string
+	Env        map[string]string
+	Pre        *InstancePre
+	Mounts     []Mount
+	Workspace  *Workspace
+	Timeout    time.Duration
+	FuelBudget uint64
+	Limits     ResourceLimits
+	// Network is the deployment's egress policy and shared HTTP client,
+	// used by host functions connecting on the guest's behalf. Nil allows
+	// public addresses only, with default HTTP limits.
+	Network *host_functions.Network
+	// Conn is an inbound connection served by the guest. It is added to the
+	// session's socket table, and the guest finds its descriptor in the
+	// ConnFDEnv environment variable and its peer in ConnRemoteAddrEnv.
+	Conn net.Conn
+	// KV backs the guest's host_kv functions. Nil makes them fail with
+	// host_functions.ErrCodeUnavailable.
+	KV kv.Store
+	// Secrets backs the guest's host_secret_get. Nil makes it fail with
+	// host_functions.ErrCodeUnavailable.
+	Secrets host_functions.SecretStore
+	// LogLimit bounds the guest's host_log records. The zero value applies
+	// host_functions.DefaultLogLimit.
+	LogLimit host_functions.LogLimit
+	// Metrics aggregates the guest's host_metric samples. Nil makes it fail
+	// with host_functions.ErrCodeUnavailable.
+	Metrics host_functions.MetricSink
+	// Invoker runs the deployments the guest calls with host_invoke. Nil
+	// makes it fail with host_functions.ErrCodeUnavailable.
+	Invoker host_functions.Invoker
+
+	// host holds the session's sockets and other host-side resources. It is
+	// shared by all of the session's stores and released by Close.
+	host   *host_functions.State
+	connFD int
 }
 
-// NewSession is a constructor to ensure all resources are initialized correctly.
-func NewSession(id uuid.UUID, engine *wasmtime.Engine, module *wasmtime.Module, args []string) (*Session, error) {
-	stdin, stdout, err := CreateIoDescriptors(id)
-	if err != nil {
-		return nil, err
-	}
+// Environment variables describing Session.Conn to the guest.
+const (
+	ConnFDEnv         = "IGNIS_SOCKET_FD"
+	ConnRemoteAddrEnv = "IGNIS_REMOTE_ADDR"
+)
 
+// NewSession is a constructor to ensure all resources are initialized correctly.
+func NewSession(id uuid.UUID, pre *InstancePre, args []string) *Session {
 	return &Session{
-		ID:     id,
-		Args:   args,
-		Engine: engine,
-		Module: module,
-		Stdin:  stdin,
-		Stdout: stdout,
-	}, nil
+		ID:   id,
+		Args: args,
+		Pre:  pre,
+	}
 }
 
-// NewStore configures the WASI environment and links host functions.
-func (s *Session) NewStore() (*wasmtime.Store, *wasmtime.Linker, error) {
-	store := wasmtime.NewStore(s.Engine)
-	linker := wasmtime.NewLinker(s.Engine)
+// NewStore creates a store with the session's limits and WASI environment.
+// Host functions live on the shared linker held by the session's InstancePre.
+//
+// The guest reads stdin from memory and its stdout and stderr are written to
+// stdout and stderr, so no file is involved. Both may still be written to
+// until the store is closed.
+func (s *Session) NewStore(stdin []byte, stdout, stderr io.Writer) (*wasmtime.Store, error) {
+	if s.host == nil {
+		s.host = host_functions.NewState(s.ID, s.Network)
+		s.host.KV = s.KV
+		s.host.Secrets = s.Secrets
+		s.host.Metrics = s.Metrics
+		s.host.Invoker = s.Invoker
+		if s.LogLimit != (host_functions.LogLimit{}) {
+			s.host.Logs = host_functions.NewGuestLogs(s.LogLimit)
+		}
+		if s.Conn != nil {
+			// The table is empty, so the connection always fits
+			s.connFD, _ = s.host.Sockets.Add(host_functions.NewRealConnection(s.Conn))
+		}
+	}
+	store := wasmtime.NewStoreWithData(s.Pre.Engine(), s.host)
+	s.Limits.apply(store)
 
 	wasiConfig := wasmtime.NewWasiConfig()
-	wasiConfig.SetStdinFile(s.Stdin.Name())
-	wasiConfig.SetStdoutFile(s.Stdout.Name())
-	wasiConfig.InheritStderr()
-	wasiConfig.InheritEnv()
+	setStdinBytes(wasiConfig, stdin)
+	setStdoutWriter(wasiConfig, stdout)
+	setStderrWriter(wasiConfig, stderr)
+	wasiConfig.SetEnv(s.envVars())
 	wasiConfig.SetArgv(s.Args)
 
-	dir, err := s.getPreopenDir()
+	mounts, err := s.mounts()
 	if err != nil {
-		return nil, nil, err
+		store.Close()
+		return nil, err
+	}
+	for _, m := range mounts {
+		if err := wasiConfig.PreopenDir(m.HostPath, m.GuestPath, m.DirPerms, m.FilePerms); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("preopen %s: %w", m.GuestPath, err)
+		}
 	}
-	wasiConfig.PreopenDir(dir, "/", wasmtime.DIR_READ, wasmtime.FILE_READ|wasmtime.FILE_WRITE)
 
 	store.SetWasi(wasiConfig)
 
-	if err := linker.DefineWasi(); err != nil {
-		return nil, nil, fmt.Errorf("wasi link: %w", err)
+	return store, nil
+}
+
+// Run executes the module. Note that for modern WASI, the Linker
+// often handles finding '_start' automatically during instantiation.
+//
+// Execution is interrupted once the tighter of the session timeout and the
+// context deadline passes, in which case ErrExecutionTimeout is returned.
+func (s *Session) Run(ctx context.Context, store *wasmtime.Store) error {
+	timeout, err := s.executionTimeout(ctx)
+	if err != nil {
+		return err
+	}
+	var guard *quotaGuard
+	if s.Workspace != nil && s.Workspace.guarded() {
+		guard = newQuotaGuard(s.Workspace, timeout)
+		setEpochCallback(store, guard.check)
+		store.SetEpochDeadline(guard.next())
+	} else {
+		store.SetEpochDeadline(epochTicks(timeout))
 	}
 
-	if err := host_functions.Link(store, linker); err != nil {
-		return nil, nil, fmt.Errorf("host functions link: %w", err)
+	// Host calls share the guest's deadline
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if s.host != nil {
+		s.host.SetContext(ctx)
 	}
 
-	return store, linker, nil
-}
+	if err := store.SetFuel(s.fuelBudget()); err != nil {
+		return fmt.Errorf("fuel setup failed: %w", err)
+	}
 
-// Run executes the module. Note that for modern WASI, the Linker
-// often handles finding '_start' automatically during instantiation.
-func (s *Session) Run(store *wasmtime.Store, linker *wasmtime.Linker) error {
-	instance, err := linker.Instantiate(store, s.Module)
+	instance, err := s.Pre.Instantiate(store)
 	if err != nil {
-		return fmt.Errorf("instantiation failed: %w", err)
+		return fmt.Errorf("instantiation failed: %w", s.classifyFailure(guard.reason(err)))
 	}
 
 	// Modern WASI check: some modules use _start, some use a default linker entry
@@ -101,29 +189,124 @@ func (s *Session) Run(store *wasmtime.Store, linker *wasmtime.Linker) error {
 	if err != nil {
 		if exitErr, ok := err.(*wasmtime.Error); ok {
 			if code, ok := exitErr.ExitStatus(); ok && code == 0 {
-				return nil
+				return s.checkWorkspace()
 			}
 		}
-		return fmt.Errorf("execution error: %w", err)
+		return fmt.Errorf("execution error: %w", s.classifyFailure(guard.reason(err)))
+	}
+
+	return s.checkWorkspace()
+}
+
+// Close releases the host resources the session's guests left open, such as
+// socket connections.
+func (s *Session) Close() error {
+	if s.host == nil {
+		return nil
+	}
+	return s.host.Close()
+}
+
+// checkWorkspace fails the execution if it left the workspace over quota.
+func (s *Session) checkWorkspace() error {
+	if s.Workspace == nil {
+		return nil
+	}
+	err := s.Workspace.checkQuota()
+	recordLimitExceeded(s.ID, err)
+	return err
+}
+
+// classifyFailure maps failures caused by the host's resource controls to
+// typed errors and records them in metrics.
+func (s *Session) classifyFailure(err error) error {
+	err = classifyPoolExhausted(classifyTrap(err))
+	if !errors.Is(err, ErrExecutionTimeout) && !errors.Is(err, ErrFuelExhausted) && !errors.Is(err, ErrInstancePoolExhausted) {
+		err = s.Limits.classify(err)
+	}
+	recordLimitExceeded(s.ID, err)
+	return err
+}
+
+// executionTimeout returns how long the guest may run, taking the context
+// deadline into account. It fails fast when the context is already done.
+func (s *Session) executionTimeout(ctx context.Context) (time.Duration, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecutionTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	if timeout <= 0 {
+		return 0, ErrExecutionTimeout
 	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return timeout, nil
+}
+
+// LogRecords returns the host_log records emitted since the last call and
+// how many were dropped.
+func (s *Session) LogRecords() ([]host_functions.LogRecord, int) {
+	if s.host == nil {
+		return nil, 0
+	}
+	return s.host.Logs.Drain()
+}
+
+// Stats reports the fuel consumed so far by the given store.
+func (s *Session) Stats(store *wasmtime.Store) ExecutionStats {
+	stats := ExecutionStats{FuelBudget: s.fuelBudget()}
+	if remaining, err := store.GetFuel(); err == nil && remaining <= stats.FuelBudget {
+		stats.FuelConsumed = stats.FuelBudget - remaining
+	}
+	return stats
+}
 
-	return nil
+func (s *Session) fuelBudget() uint64 {
+	if s.FuelBudget == 0 {
+		return DefaultFuelBudget
+	}
+	return s.FuelBudget
 }
 
-func (s *Session) getPreopenDir() (string, error) {
-	if s.PreOpenedDir != "" {
-		return s.PreOpenedDir, nil
+// envVars returns the guest environment as parallel key and value slices,
+// sorted by key. Nothing is inherited from the host.
+func (s *Session) envVars() (keys, values []string) {
+	env := s.Env
+	if s.Conn != nil {
+		env = make(map[string]string, len(s.Env)+2)
+		for key, value := range s.Env {
+			env[key] = value
+		}
+		env[ConnFDEnv] = strconv.Itoa(s.connFD)
+		env[ConnRemoteAddrEnv] = s.Conn.RemoteAddr().String()
+	}
+	keys = make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
 	}
-	return os.Getwd()
+	sort.Strings(keys)
+	values = make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = env[key]
+	}
+	return keys, values
 }
 
-// Cleanup ensures temporary resources are released.
-func (s *Session) Cleanup() {
-	cleanupSessionDescriptors(s)
-	if s.Engine != nil {
-		s.Engine.Close()
+// mounts returns the directories preopened for the guest. Nothing but these
+// is visible, and there is no fallback to the server's working directory.
+func (s *Session) mounts() ([]Mount, error) {
+	if s.Workspace == nil {
+		return s.Mounts, nil
 	}
-	if s.Module != nil {
-		s.Module.Close()
+	workspace, err := s.Workspace.mount()
+	if err != nil {
+		return nil, err
 	}
+	return append(append([]Mount(nil), s.Mounts...), workspace), nil
 }
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoSandboxRoot is returned when a directory is requested but no sandbox
+// root is configured.
+var ErrNoSandboxRoot = errors.New("no sandbox root configured")
+
+// ErrOutsideSandbox is returned for directories that resolve outside the
+// sandbox root.
+var ErrOutsideSandbox = errors.New("path escapes the sandbox root")
+
+// CleanSandboxPath normalizes dir, a path relative to the sandbox root, and
+// rejects absolute paths and paths climbing out of the root. It doesn't touch
+// the filesystem, so the directory need not exist yet.
+func CleanSandboxPath(dir string) (string, error) {
+	if filepath.IsAbs(dir) {
+		return "", fmt.Errorf("%w: %s is absolute", ErrOutsideSandbox, dir)
+	}
+	clean := filepath.Clean(dir)
+	if !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideSandbox, dir)
+	}
+	return clean, nil
+}
+
+// SandboxPath resolves dir, relative to root, to a host directory. Symlinks
+// are followed, and the result must still lie within root and be a directory.
+func SandboxPath(root, dir string) (string, error) {
+	if root == "" {
+		return "", ErrNoSandboxRoot
+	}
+	clean, err := CleanSandboxPath(dir)
+	if err != nil {
+		return "", err
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("sandbox root: %w", err)
+	}
+	if realRoot, err = filepath.Abs(realRoot); err != nil {
+		return "", fmt.Errorf("sandbox root: %w", err)
+	}
+
+	path, err := filepath.EvalSymlinks(filepath.Join(realRoot, clean))
+	if err != nil {
+		return "", relativePathError(dir, err)
+	}
+	if !within(realRoot, path) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideSandbox, dir)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", relativePathError(dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return path, nil
+}
+
+// relativePathError reports err against dir rather than the host path, which
+// would reveal the sandbox root to the deployment's callers.
+func relativePathError(dir string, err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return fmt.Errorf("%s: %w", dir, err)
+}
+
+// within reports whether path is root or lies beneath it. Both must be
+// absolute and clean.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
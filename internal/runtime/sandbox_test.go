@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCleanSandboxPath(t *testing.T) {
+	tests := []struct {
+		dir     string
+		want    string
+		escapes bool
+	}{
+		{"data", "data", false},
+		{"data/./sub/", "data/sub", false},
+		{"data/../other", "other", false},
+		{".", ".", false},
+		{"..", "", true},
+		{"../root", "", true},
+		{"data/../../root", "", true},
+		{"/etc", "", true},
+	}
+	for _, tt := range tests {
+		got, err := CleanSandboxPath(tt.dir)
+		if tt.escapes {
+			if !errors.Is(err, ErrOutsideSandbox) {
+				t.Errorf("CleanSandboxPath(%q) = %q, %v, want ErrOutsideSandbox", tt.dir, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("CleanSandboxPath(%q) = %q, %v, want %q", tt.dir, got, err, tt.want)
+		}
+	}
+}
+
+func TestSandboxPath(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{
+		filepath.Join(root, "data", "sub"),
+		filepath.Join(root, "other"),
+		outside,
+	} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "file"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"inside":         filepath.Join(root, "other"),
+		"inside-rel":     "data/sub",
+		"absolute":       outside,
+		"relative":       "../outside",
+		"data/sub/climb": "../../../outside",
+		"chain":          "absolute",
+		"dangling":       filepath.Join(root, "missing"),
+		"parent":         "..",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("creating symlinks: %v", err)
+		}
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir     string
+		want    string // relative to root; empty for an error
+		escapes bool
+	}{
+		{".", ".", false},
+		{"data", "data", false},
+		{"data/sub", "data/sub", false},
+		{"inside", "other", false},
+		{"inside-rel", "data/sub", false},
+		{"absolute", "", true},
+		{"relative", "", true},
+		{"data/sub/climb", "", true},
+		{"chain", "", true},
+		{"parent", "", true},
+		{"absolute/..", ".", false}, // cleaned before symlinks are followed
+		{"../outside", "", true},
+		{outside, "", true},
+		{"dangling", "", false},
+		{"missing", "", false},
+		{"file", "", false},
+	}
+	for _, tt := range tests {
+		got, err := SandboxPath(root, tt.dir)
+		switch {
+		case tt.escapes:
+			if !errors.Is(err, ErrOutsideSandbox) {
+				t.Errorf("SandboxPath(%q) = %q, %v, want ErrOutsideSandbox", tt.dir, got, err)
+			}
+		case tt.want == "":
+			if err == nil {
+				t.Errorf("SandboxPath(%q) = %q, want an error", tt.dir, got)
+			} else if errors.Is(err, ErrOutsideSandbox) {
+				t.Errorf("SandboxPath(%q) = %v, want an error other than ErrOutsideSandbox", tt.dir, err)
+			}
+		default:
+			if want := filepath.Join(realRoot, tt.want); err != nil || got != want {
+				t.Errorf("SandboxPath(%q) = %q, %v, want %q", tt.dir, got, err, want)
+			}
+		}
+	}
+
+	if _, err := SandboxPath("", "data"); !errors.Is(err, ErrNoSandboxRoot) {
+		t.Errorf("SandboxPath without a root = %v, want ErrNoSandboxRoot", err)
+	}
+
+	// The root itself may be reached through a symlink
+	linkedRoot := filepath.Join(base, "linked-root")
+	if err := os.Symlink(root, linkedRoot); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := SandboxPath(linkedRoot, "data"); err != nil || got != filepath.Join(realRoot, "data") {
+		t.Errorf("SandboxPath through a linked root = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,99 @@
+package runtime
+
+// The Go bindings only accept file paths for guest stdio. libwasmtime can also
+// serve stdin from a byte vector and hand stdout and stderr writes to a
+// callback, which keeps request and response bodies and guest logs in memory.
+// The prototypes below mirror wasi.h and wasm.h.
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct wasi_config_t wasi_config_t;
+typedef struct wasm_byte_vec_t {
+	size_t size;
+	char *data;
+} wasm_byte_vec_t;
+
+extern void wasm_byte_vec_new(wasm_byte_vec_t *out, size_t size, const char *data);
+extern void wasi_config_set_stdin_bytes(wasi_config_t *config, wasm_byte_vec_t *binary);
+extern void wasi_config_set_stdout_custom(wasi_config_t *config,
+	ptrdiff_t (*callback)(void *, const unsigned char *, size_t), void *data,
+	void (*finalizer)(void *));
+extern void wasi_config_set_stderr_custom(wasi_config_t *config,
+	ptrdiff_t (*callback)(void *, const unsigned char *, size_t), void *data,
+	void (*finalizer)(void *));
+
+extern ptrdiff_t ignisStdioWrite(void *data, unsigned char *buf, size_t len);
+extern void ignisReleaseHandle(void *data);
+*/
+import "C"
+
+import (
+	"io"
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+)
+
+// eio is the errno reported to the guest when its output cannot be written.
+const eio = 5
+
+// setStdinBytes serves data to the guest as its stdin.
+func setStdinBytes(config *wasmtime.WasiConfig, data []byte) {
+	var vec C.wasm_byte_vec_t
+	var ptr *C.char
+	if len(data) > 0 {
+		ptr = (*C.char)(unsafe.Pointer(&data[0]))
+	}
+	// wasm_byte_vec_new copies data; Wasmtime takes ownership of the copy.
+	C.wasm_byte_vec_new(&vec, C.size_t(len(data)), ptr)
+	C.wasi_config_set_stdin_bytes(wasiConfigPtr(config), &vec)
+}
+
+// setStdoutWriter forwards everything the guest writes to its stdout to w.
+// w is released once the store owning the WASI context is closed.
+func setStdoutWriter(config *wasmtime.WasiConfig, w io.Writer) {
+	C.wasi_config_set_stdout_custom(wasiConfigPtr(config),
+		(*[0]byte)(C.ignisStdioWrite), newHandle(w), (*[0]byte)(C.ignisReleaseHandle))
+}
+
+// setStderrWriter forwards everything the guest writes to its stderr to w.
+// w is released once the store owning the WASI context is closed.
+func setStderrWriter(config *wasmtime.WasiConfig, w io.Writer) {
+	C.wasi_config_set_stderr_custom(wasiConfigPtr(config),
+		(*[0]byte)(C.ignisStdioWrite), newHandle(w), (*[0]byte)(C.ignisReleaseHandle))
+}
+
+// newHandle wraps v in a handle stored in C memory, since C can't keep Go
+// pointers. It is freed by ignisReleaseHandle.
+func newHandle(v any) unsafe.Pointer {
+	data := (*C.uintptr_t)(C.malloc(C.size_t(unsafe.Sizeof(C.uintptr_t(0)))))
+	*data = C.uintptr_t(cgo.NewHandle(v))
+	return unsafe.Pointer(data)
+}
+
+//export ignisStdioWrite
+func ignisStdioWrite(data unsafe.Pointer, buf *C.uchar, n C.size_t) C.ptrdiff_t {
+	w := cgo.Handle(*(*C.uintptr_t)(data)).Value().(io.Writer)
+	written, err := w.Write(unsafe.Slice((*byte)(buf), int(n)))
+	if err != nil && written == 0 {
+		return -eio
+	}
+	return C.ptrdiff_t(written)
+}
+
+//export ignisReleaseHandle
+func ignisReleaseHandle(data unsafe.Pointer) {
+	cgo.Handle(*(*C.uintptr_t)(data)).Delete()
+	C.free(data)
+}
+
+// wasiConfigPtr returns the wasi_config_t behind config. wasmtime.WasiConfig is
+// a struct holding only that pointer; this breaks if the bindings change its
+// layout.
+func wasiConfigPtr(config *wasmtime.WasiConfig) *C.wasi_config_t {
+	return (*struct{ ptr *C.wasi_config_t })(unsafe.Pointer(config)).ptr
+}
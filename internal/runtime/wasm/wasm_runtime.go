@@ -1,29 +1,47 @@
 package wasm
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"os"
+	"net"
+	"time"
 
-	"github.com/bytecodealliance/wasmtime-go/v41"
 	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/models"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
 )
 
 type WasmRuntime struct {
-	session runtime.Session
+	session     runtime.Session
+	maxLogBytes int
+	stats       runtime.ExecutionStats
+	logs        runtime.ExecutionLog
 }
 
 // runtimeConfig handles the configuration of a WasmRuntime
 type runtimeConfig struct {
-	id               uuid.UUID
-	serializedModule []byte
-	preopenedDir     string
-	args             []string
-	hash             string
-	err              error
+	id           uuid.UUID
+	pre          *runtime.InstancePre
+	preopenedDir string
+	workspace    *runtime.Workspace
+	network      *host_functions.Network
+	conn         net.Conn
+	kv           kv.Store
+	secrets      host_functions.SecretStore
+	logLimit     host_functions.LogLimit
+	metrics      host_functions.MetricSink
+	invoker      host_functions.Invoker
+	args         []string
+	env          map[string]string
+	timeout      time.Duration
+	fuelBudget   uint64
+	limits       runtime.ResourceLimits
+	maxLogBytes  int
+	hash         string
+	err          error
 }
 
 // NewRuntimeConfig initializes a new builder with the required ID
@@ -34,22 +52,110 @@ func NewRuntimeConfig(id uuid.UUID) *runtimeConfig {
 	return &runtimeConfig{id: id}
 }
 
-// WithSerializedModule provides pre-compiled bytes (e.g., fetched from Redis)
-func (b *runtimeConfig) WithSerializedModule(data []byte) *runtimeConfig {
-	b.serializedModule = data
+// WithInstancePre provides the pre-linked module. It is shared across
+// executions and outlives the runtime.
+func (b *runtimeConfig) WithInstancePre(pre *runtime.InstancePre) *runtimeConfig {
+	b.pre = pre
 	return b
 }
 
+// WithPreopenedDir exposes a host directory to the module as "/". Callers
+// are expected to have confined it to the sandbox root.
 func (b *runtimeConfig) WithPreopenedDir(dir string) *runtimeConfig {
 	b.preopenedDir = dir
 	return b
 }
 
+// WithWorkspace mounts the deployment's private workspace at
+// runtime.WorkspaceGuestPath
+func (b *runtimeConfig) WithWorkspace(ws *runtime.Workspace) *runtimeConfig {
+	b.workspace = ws
+	return b
+}
+
+// WithArgs sets the arguments passed to the module after its program name
 func (b *runtimeConfig) WithArgs(args []string) *runtimeConfig {
 	b.args = args
 	return b
 }
 
+// WithTimeout bounds the wall-clock time of each execution
+func (b *runtimeConfig) WithTimeout(timeout time.Duration) *runtimeConfig {
+	b.timeout = timeout
+	return b
+}
+
+// WithFuelBudget sets the amount of fuel each execution may consume
+func (b *runtimeConfig) WithFuelBudget(fuel uint64) *runtimeConfig {
+	b.fuelBudget = fuel
+	return b
+}
+
+// WithLimits caps the memory, tables and instances each execution may allocate
+func (b *runtimeConfig) WithLimits(limits runtime.ResourceLimits) *runtimeConfig {
+	b.limits = limits
+	return b
+}
+
+// WithEnv sets the environment variables visible to the guest. The host
+// environment is never inherited.
+func (b *runtimeConfig) WithEnv(env map[string]string) *runtimeConfig {
+	b.env = env
+	return b
+}
+
+// WithMaxLogBytes caps the guest stderr kept from each execution
+func (b *runtimeConfig) WithMaxLogBytes(max int) *runtimeConfig {
+	b.maxLogBytes = max
+	return b
+}
+
+// WithNetwork sets the deployment's egress policy and shared HTTP client,
+// used when the module connects out through host functions. Without it only
+// public addresses are reachable
+func (b *runtimeConfig) WithNetwork(network *host_functions.Network) *runtimeConfig {
+	b.network = network
+	return b
+}
+
+// WithConnection hands an inbound connection to the module, which finds it
+// through runtime.ConnFDEnv. The runtime serves that one connection and
+// closes it with Close.
+func (b *runtimeConfig) WithConnection(conn net.Conn) *runtimeConfig {
+	b.conn = conn
+	return b
+}
+
+// WithKV sets the store behind the module's host_kv functions
+func (b *runtimeConfig) WithKV(kv kv.Store) *runtimeConfig {
+	b.kv = kv
+	return b
+}
+
+// WithSecrets sets the store behind the module's host_secret_get
+func (b *runtimeConfig) WithSecrets(secrets host_functions.SecretStore) *runtimeConfig {
+	b.secrets = secrets
+	return b
+}
+
+// WithMetrics sets the sink aggregating the module's host_metric samples
+func (b *runtimeConfig) WithMetrics(metrics host_functions.MetricSink) *runtimeConfig {
+	b.metrics = metrics
+	return b
+}
+
+// WithInvoker sets what runs the deployments the module calls with host_invoke
+func (b *runtimeConfig) WithInvoker(invoker host_functions.Invoker) *runtimeConfig {
+	b.invoker = invoker
+	return b
+}
+
+// WithLogLimit bounds the records the module emits with host_log
+func (b *runtimeConfig) WithLogLimit(limit host_functions.LogLimit) *runtimeConfig {
+	b.logLimit = limit
+	return b
+}
+
 func (b *runtimeConfig) Type() models.RuntimeType {
 	return models.RuntimeTypeWASM
 }
@@ -60,36 +166,35 @@ func (b *runtimeConfig) Instantiate() (runtime.Runtime, error) {
 		return nil, b.err
 	}
 
-	engine := wasmtime.NewEngine()
-	var module *wasmtime.Module
-	var err error
-
-	// 1. Attempt to use Serialized Module first (Cache Hit path)
-	if len(b.serializedModule) > 0 {
-		module, err = wasmtime.NewModuleDeserialize(engine, b.serializedModule)
-		if err != nil {
-			// If deserialization fails, we don't return error yet,
-			// we try to fall back to raw compilation if available.
-			module = nil
-		}
+	if b.pre == nil {
+		return nil, fmt.Errorf("no compiled module provided")
 	}
 
-	// Setup IO descriptors in /dev/shm
-	stdinFile, stdoutFile, err := runtime.CreateIoDescriptors(b.id)
-	if err != nil {
-		return nil, fmt.Errorf("io setup failed: %w", err)
+	var mounts []runtime.Mount
+	if b.preopenedDir != "" {
+		mounts = append(mounts, runtime.ReadWriteMount(b.preopenedDir, "/"))
 	}
 
 	return &WasmRuntime{
 		session: runtime.Session{
-			ID:           b.id,
-			Args:         b.args,
-			Engine:       engine,
-			Module:       module,
-			Stdin:        stdinFile,
-			Stdout:       stdoutFile,
-			PreOpenedDir: b.preopenedDir,
+			ID:         b.id,
+			Env:        b.env,
+			Args:       append([]string{b.id.String()}, b.args...),
+			Pre:        b.pre,
+			Mounts:     mounts,
+			Workspace:  b.workspace,
+			Network:    b.network,
+			Conn:       b.conn,
+			KV:         b.kv,
+			Secrets:    b.secrets,
+			LogLimit:   b.logLimit,
+			Metrics:    b.metrics,
+			Invoker:    b.invoker,
+			Timeout:    b.timeout,
+			FuelBudget: b.fuelBudget,
+			Limits:     b.limits,
 		},
+		maxLogBytes: b.maxLogBytes,
 	}, nil
 }
 
@@ -100,47 +205,41 @@ func (r *WasmRuntime) Execute(ctx context.Context, fdRequest any) ([]byte, error
 		return nil, fmt.Errorf("expected []byte for fdRequest")
 	}
 
-	// Reset Stdin
-	if err := r.resetFile(r.session.Stdin); err != nil {
-		return nil, err
-	}
-
-	if _, err := r.session.Stdin.Write(reqBytes); err != nil {
-		return nil, fmt.Errorf("failed to write to WASM stdin: %w", err)
-	}
-	_ = r.session.Stdin.Sync()
-
-	// Setup Store & Linker
-	store, linker, err := r.session.NewStore()
+	// Setup Store with the request as stdin
+	var stdout bytes.Buffer
+	stderr := runtime.NewLogBuffer(r.maxLogBytes)
+	store, err := r.session.NewStore(reqBytes, &stdout, stderr)
 	if err != nil {
 		return nil, err
 	}
-	defer store.Close()
 
 	// Run
-	if err := r.session.Run(store, linker); err != nil {
+	err = r.session.Run(ctx, store)
+	r.stats = r.session.Stats(store)
+
+	// Closing the store releases the guest's stdio, so the buffers are final
+	store.Close()
+	r.logs = stderr.Log()
+	r.logs.Records, r.logs.RecordsDropped = r.session.LogRecords()
+	if err != nil {
 		return nil, err
 	}
 
-	// Read Output
-	if _, err := r.session.Stdout.Seek(0, io.SeekStart); err != nil {
-		return nil, fmt.Errorf("failed to seek stdout: %w", err)
-	}
+	return stdout.Bytes(), nil
+}
 
-	return io.ReadAll(r.session.Stdout)
+// Stats reports resource usage of the most recent execution
+func (r *WasmRuntime) Stats() runtime.ExecutionStats {
+	return r.stats
 }
 
-func (r *WasmRuntime) resetFile(f *os.File) error {
-	if _, err := f.Seek(0, io.SeekStart); err != nil {
-		return fmt.Errorf("seek failed: %w", err)
-	}
-	if err := f.Truncate(0); err != nil {
-		return fmt.Errorf("truncate failed: %w", err)
-	}
-	return nil
+// Logs returns the guest stderr and host_log records captured during the
+// most recent execution
+func (r *WasmRuntime) Logs() runtime.ExecutionLog {
+	return r.logs
 }
 
+// Close releases the runtime, closing any connections the module left open
 func (r *WasmRuntime) Close(ctx context.Context) error {
-	r.session.Cleanup()
-	return nil
+	return r.session.Close()
 }
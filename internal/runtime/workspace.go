@@ -0,0 +1,224 @@
+package runtime
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v41"
+	"github.com/google/uuid"
+)
+
+// WorkspaceGuestPath is where a deployment's workspace is mounted in the guest.
+const WorkspaceGuestPath = "/data"
+
+// Workspace lifetimes selectable per deployment.
+const (
+	// WorkspaceNone mounts no workspace, so nothing is created on the host.
+	WorkspaceNone = "none"
+	// WorkspaceEphemeral gives each execution an empty workspace that is
+	// removed once the execution ends.
+	WorkspaceEphemeral = "ephemeral"
+	// WorkspacePersistent keeps one workspace per deployment across executions.
+	WorkspacePersistent = "persistent"
+)
+
+// Mount exposes a host directory to the guest.
+type Mount struct {
+	HostPath  string
+	GuestPath string
+	DirPerms  wasmtime.WasiDirPerms
+	FilePerms wasmtime.WasiFilePerms
+}
+
+// ReadOnlyMount exposes hostPath at guestPath without write access.
+func ReadOnlyMount(hostPath, guestPath string) Mount {
+	return Mount{HostPath: hostPath, GuestPath: guestPath, DirPerms: wasmtime.DIR_READ, FilePerms: wasmtime.FILE_READ}
+}
+
+// ReadWriteMount exposes hostPath at guestPath with full access.
+func ReadWriteMount(hostPath, guestPath string) Mount {
+	return Mount{
+		HostPath:  hostPath,
+		GuestPath: guestPath,
+		DirPerms:  wasmtime.DIR_READ | wasmtime.DIR_WRITE,
+		FilePerms: wasmtime.FILE_READ | wasmtime.FILE_WRITE,
+	}
+}
+
+// The workspace of a running guest is measured against its quota every
+// epoch tick, or less often for workspaces slow to measure: measuring takes
+// at most a tenth of the guest's time, with at most maxWorkspaceCheckInterval
+// between two checks.
+const (
+	workspaceCheckCost        = 10
+	maxWorkspaceCheckInterval = time.Second
+)
+
+// Workspace is a deployment's private directory, mounted at
+// WorkspaceGuestPath. Its size is held to a quota while the guest runs:
+// Wasmtime writes straight to the host filesystem, so a write in flight can't
+// be refused, but the workspace is measured periodically and the guest is
+// stopped once it is over quota. A workspace left at its quota is mounted
+// read-only.
+type Workspace struct {
+	Path       string
+	QuotaBytes int64
+	ephemeral  bool
+	// readOnly is set when the workspace is mounted read-only, so it can't
+	// grow and needn't be measured.
+	readOnly bool
+}
+
+// usages holds the last measured size of each persistent workspace, by path,
+// so mounting one doesn't walk it again.
+var usages sync.Map
+
+// OpenWorkspace creates or reopens the workspace of a deployment under root.
+// It returns nil for WorkspaceNone; an empty mode is WorkspaceEphemeral, the
+// default. A quota of zero or less disables the size check.
+func OpenWorkspace(root string, id uuid.UUID, mode string, quotaBytes int64) (*Workspace, error) {
+	switch mode {
+	case WorkspaceNone:
+		return nil, nil
+	case WorkspacePersistent:
+		path := filepath.Join(root, WorkspacePersistent, id.String())
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			return nil, fmt.Errorf("workspace: %w", err)
+		}
+		return &Workspace{Path: path, QuotaBytes: quotaBytes}, nil
+	case WorkspaceEphemeral, "":
+		parent := filepath.Join(root, WorkspaceEphemeral)
+		if err := os.MkdirAll(parent, 0o700); err != nil {
+			return nil, fmt.Errorf("workspace: %w", err)
+		}
+		path, err := os.MkdirTemp(parent, id.String()+"-*")
+		if err != nil {
+			return nil, fmt.Errorf("workspace: %w", err)
+		}
+		return &Workspace{Path: path, QuotaBytes: quotaBytes, ephemeral: true}, nil
+	default:
+		return nil, fmt.Errorf("workspace: unknown mode %q", mode)
+	}
+}
+
+// Usage returns the total size of the regular files in the workspace.
+func (w *Workspace) Usage() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(w.Path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if err == nil && !w.ephemeral {
+		usages.Store(w.Path, total)
+	}
+	return total, err
+}
+
+// mount returns how the workspace is exposed to the next execution: read-only
+// once it is at its quota. An ephemeral workspace starts empty, and a
+// persistent one is only walked when its last measured size is at its quota,
+// in case it shrank since.
+func (w *Workspace) mount() (Mount, error) {
+	if w.QuotaBytes <= 0 || w.ephemeral {
+		return ReadWriteMount(w.Path, WorkspaceGuestPath), nil
+	}
+	usage, ok := usages.Load(w.Path)
+	if !ok || usage.(int64) >= w.QuotaBytes {
+		measured, err := w.Usage()
+		if err != nil {
+			return Mount{}, fmt.Errorf("workspace usage: %w", err)
+		}
+		usage = measured
+	}
+	if usage.(int64) >= w.QuotaBytes {
+		w.readOnly = true
+		return ReadOnlyMount(w.Path, WorkspaceGuestPath), nil
+	}
+	return ReadWriteMount(w.Path, WorkspaceGuestPath), nil
+}
+
+// guarded reports whether executions must be watched for growing the
+// workspace past its quota.
+func (w *Workspace) guarded() bool {
+	return w.QuotaBytes > 0 && !w.readOnly
+}
+
+// checkQuota returns a *LimitError if the workspace grew past its quota.
+func (w *Workspace) checkQuota() error {
+	if !w.guarded() {
+		return nil
+	}
+	usage, err := w.Usage()
+	if err != nil {
+		return fmt.Errorf("workspace usage: %w", err)
+	}
+	if usage > w.QuotaBytes {
+		return &LimitError{Resource: ResourceDisk, Limit: w.QuotaBytes}
+	}
+	return nil
+}
+
+// quotaGuard stops a guest whose workspace grows past its quota. It is called
+// back on the store's epoch deadline, which then no longer traps, so it also
+// stops the guest at its execution deadline.
+type quotaGuard struct {
+	workspace *Workspace
+	deadline  time.Time
+	// interval is the time until the next check.
+	interval time.Duration
+	// err is why the guest was stopped, if it was.
+	err error
+}
+
+func newQuotaGuard(w *Workspace, timeout time.Duration) *quotaGuard {
+	return &quotaGuard{workspace: w, deadline: time.Now().Add(timeout), interval: EpochTickInterval}
+}
+
+// next returns the ticks until the guard is called again.
+func (g *quotaGuard) next() uint64 {
+	return min(epochTicks(time.Until(g.deadline)), epochTicks(g.interval))
+}
+
+// reason returns why the guard stopped the guest, or err if it didn't. It is
+// safe to call on a nil guard.
+func (g *quotaGuard) reason(err error) error {
+	if g != nil && g.err != nil {
+		return g.err
+	}
+	return err
+}
+
+func (g *quotaGuard) check() (uint64, error) {
+	if !time.Now().Before(g.deadline) {
+		g.err = ErrExecutionTimeout
+		return 0, g.err
+	}
+	start := time.Now()
+	if err := g.workspace.checkQuota(); err != nil {
+		g.err = err
+		return 0, err
+	}
+	g.interval = min(max(workspaceCheckCost*time.Since(start), EpochTickInterval), maxWorkspaceCheckInterval)
+	return g.next(), nil
+}
+
+// Close removes an ephemeral workspace. Persistent workspaces are kept.
+func (w *Workspace) Close() error {
+	if w == nil || !w.ephemeral {
+		return nil
+	}
+	return os.RemoveAll(w.Path)
+}
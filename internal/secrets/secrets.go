@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of the decoded encryption key, selecting AES-256.
+const KeySize = 32
+
+// ErrNoKey is returned when secrets are used without an encryption key.
+var ErrNoKey = errors.New("no encryption key configured")
+
+// Cipher encrypts secret values at rest with AES-256-GCM. A nil *Cipher is
+// valid and fails every operation with ErrNoKey.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher from a base64-encoded 32-byte key.
+func NewCipher(encodedKey string) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode key: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt seals plaintext and returns it base64-encoded along with its nonce.
+// The same context must be passed to Decrypt, which binds the ciphertext to
+// where it is stored.
+func (c *Cipher) Encrypt(plaintext, context string) (string, error) {
+	if c == nil {
+		return "", ErrNoKey
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with the same context.
+func (c *Cipher) Decrypt(encoded, context string) (string, error) {
+	if c == nil {
+		return "", ErrNoKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decode secret: %w", err)
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("secret too short")
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(context))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
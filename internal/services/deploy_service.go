@@ -2,23 +2,44 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/schemas"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/config"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/models"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/repository"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/secrets"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/storage"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/utils"
+	"gorm.io/gorm"
 )
 
 const (
 	s3PathFormat = "%s/%s.%s"
 )
 
+// maxHostSecretBytes bounds the value of a host secret
+const maxHostSecretBytes = 64 << 10
+
+// Deployment kinds: how a deployment is invoked
+const (
+	// DeploymentKindHTTP deployments handle requests to the run endpoint
+	DeploymentKindHTTP = "http"
+	// DeploymentKindTCP deployments serve connections accepted on their
+	// listen port, each by a fresh guest instance
+	DeploymentKindTCP = "tcp"
+)
+
 // DeploymentService defines the interface for deployment operations
 type DeploymentService interface {
 	CreateDeployment(context context.Context, req schemas.DeployRequest) (*schemas.DeployResponse, error)
@@ -27,6 +48,22 @@ type DeploymentService interface {
 	ListAllDeployments(context.Context) ([]*schemas.DeployResponse, error)
 	GetDeploymentFileContentByUUID(context context.Context, id uuid.UUID) ([]byte, error)
 	GetDeploymentFileContentByHash(context context.Context, hash string) ([]byte, error)
+	// GetDeploymentEnv returns the guest environment with secrets decrypted
+	GetDeploymentEnv(context context.Context, id uuid.UUID) (map[string]string, error)
+	// SetHostSecret encrypts and stores a secret the guest reads with
+	// host_secret_get, replacing any of the same name
+	SetHostSecret(context context.Context, id uuid.UUID, name, value string) error
+	// DeleteHostSecret removes a host secret, reporting whether it existed
+	DeleteHostSecret(context context.Context, id uuid.UUID, name string) (bool, error)
+	// GetHostSecrets returns the plaintext of all of a deployment's host
+	// secrets
+	GetHostSecrets(context context.Context, id uuid.UUID) (map[string][]byte, error)
+	// DeleteDeployment removes a deployment, along with its stored file
+	// unless another deployment shares it
+	DeleteDeployment(context context.Context, id uuid.UUID) error
+	// OnDelete registers fn to be called with the ID of every deployment
+	// DeleteDeployment removes
+	OnDelete(fn func(id uuid.UUID))
 }
 
 // deploymentService implements the DeploymentService interface
@@ -34,14 +71,59 @@ type deploymentService struct {
 	deploymentRepo repository.DeploymentRepository
 	config         *config.Config
 	s3Storage      storage.S3Storage
+	cipher         *secrets.Cipher
+	// files serializes the creation and deletion of deployments sharing a
+	// stored file, by hash
+	files fileLocks
+
+	hooksMu     sync.Mutex
+	deleteHooks []func(id uuid.UUID)
 }
 
-// NewDeploymentService creates a new instance of DeploymentService
-func NewDeploymentService(runtimeRepo repository.DeploymentRepository, s3Storage storage.S3Storage, config *config.Config) DeploymentService {
+// fileLocks holds a mutex per file hash while it is in use
+type fileLocks struct {
+	mu    sync.Mutex
+	locks map[string]*fileLock
+}
+
+type fileLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks hash and returns the function unlocking it
+func (l *fileLocks) lock(hash string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*fileLock)
+	}
+	fl, ok := l.locks[hash]
+	if !ok {
+		fl = &fileLock{}
+		l.locks[hash] = fl
+	}
+	fl.refs++
+	l.mu.Unlock()
+
+	fl.Lock()
+	return func() {
+		fl.Unlock()
+		l.mu.Lock()
+		if fl.refs--; fl.refs == 0 {
+			delete(l.locks, hash)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// NewDeploymentService creates a new instance of DeploymentService. cipher may
+// be nil, in which case deployments with secrets are rejected.
+func NewDeploymentService(runtimeRepo repository.DeploymentRepository, s3Storage storage.S3Storage, config *config.Config, cipher *secrets.Cipher) DeploymentService {
 	return &deploymentService{
 		deploymentRepo: runtimeRepo,
 		config:         config,
 		s3Storage:      s3Storage,
+		cipher:         cipher,
 	}
 }
 
@@ -59,6 +141,40 @@ func (ds *deploymentService) CreateDeployment(context context.Context, req schem
 		return nil, fmt.Errorf("file extension mismatch: expected %s for %s runtime, got %s", expectedExt, req.RuntimeType, actualExt)
 	}
 
+	preopenedDir, err := ds.validateWasiOptions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaceMode, err := validateWorkspaceMode(req.WorkspaceMode)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := ds.validateKind(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := host_functions.NewEgressPolicy(req.EgressHosts, req.EgressCIDRs, req.EgressPorts); err != nil {
+		return nil, &InvalidEgressPolicyError{Err: err}
+	}
+
+	env, err := parseEnv(req.Env)
+	if err != nil {
+		return nil, err
+	}
+	plainSecrets, err := parseEnv(req.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	for name := range plainSecrets {
+		if _, ok := env[name]; ok {
+			return nil, &InvalidEnvError{Entry: name, Reason: "defined both as a variable and as a secret"}
+		}
+	}
+	if len(plainSecrets) > 0 && ds.cipher == nil {
+		return nil, fmt.Errorf("secrets require an encryption key: %w", secrets.ErrNoKey)
+	}
+
 	// Open the uploaded file
 	file, err := req.File.Open()
 	if err != nil {
@@ -75,19 +191,16 @@ func (ds *deploymentService) CreateDeployment(context context.Context, req schem
 	// Calculate the hash based on the file data
 	targetHash := utils.GetHash(filedata)
 
-	// Check if a runtime with the same hash already exists in the database
-	existingDeployment, err := ds.deploymentRepo.FindByHash(context, targetHash)
-	if err == nil && existingDeployment != nil {
-		// Runtime with same hash already exists
-		return &schemas.DeployResponse{
-			ID:          existingDeployment.ID.String(),
-			IsExisting:  true,
-			RuntimeType: existingDeployment.RuntimeType,
-			Hash:        existingDeployment.Hash,
-			S3FilePath:  existingDeployment.S3FilePath,
-			CreatedAt:   existingDeployment.CreatedAt,
-			UpdatedAt:   existingDeployment.UpdatedAt,
-		}, nil
+	// The unique index on listen_port settles concurrent deployments of
+	// the same port; this check only names the holder
+	if kind == DeploymentKindTCP {
+		holder, err := ds.deploymentRepo.FindByListenPort(context, req.ListenPort)
+		switch {
+		case err == nil:
+			return nil, &InvalidListenPortError{Port: req.ListenPort, Reason: "already used by deployment " + holder.ID.String()}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, fmt.Errorf("failed to check listen port: %w", err)
+		}
 	}
 
 	// Create new runtime with a new UUID
@@ -96,38 +209,81 @@ func (ds *deploymentService) CreateDeployment(context context.Context, req schem
 		return nil, err
 	}
 
-	// Use S3 storage
-	key := fmt.Sprintf(s3PathFormat, req.RuntimeType, id, expectedExt)
+	// Encrypt secrets, binding each value to this deployment and variable
+	encryptedSecrets := make(models.StringMap, len(plainSecrets))
+	for name, value := range plainSecrets {
+		encryptedSecrets[name], err = ds.cipher.Encrypt(value, secretContext(id, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt secret %s: %w", name, err)
+		}
+	}
 
-	// Upload to S3
-	if err := ds.s3Storage.UploadFile(context, key, filedata); err != nil {
-		return nil, fmt.Errorf("failed to upload file to S3: %w", err)
+	// Files are content-addressed, so a file already stored under the same
+	// hash is shared rather than uploaded again. Its deployment is left
+	// alone: the new one gets its own record and configuration. The hash
+	// stays locked until the record exists, so DeleteDeployment can't remove
+	// the file in between
+	unlock := ds.files.lock(targetHash)
+	defer unlock()
+	var key string
+	existingDeployment, err := ds.deploymentRepo.FindByHash(context, targetHash)
+	isExisting := err == nil && existingDeployment != nil
+	if isExisting {
+		key = existingDeployment.S3FilePath
+	} else {
+		key = fmt.Sprintf(s3PathFormat, req.RuntimeType, id, expectedExt)
+		if err := ds.s3Storage.UploadFile(context, key, filedata); err != nil {
+			return nil, fmt.Errorf("failed to upload file to S3: %w", err)
+		}
 	}
 
 	// Create the runtime record in the database
 	runtimeRecord := &models.Runtime{
-		ID:          id,
-		RuntimeType: req.RuntimeType,
-		Hash:        targetHash,
-		S3FilePath:  key, // Store the S3 key in the database
+		ID:               id,
+		RuntimeType:      req.RuntimeType,
+		Hash:             targetHash,
+		S3FilePath:       key, // Store the S3 key in the database
+		TimeoutMs:        req.TimeoutMs,
+		FuelBudget:       req.FuelBudget,
+		MaxMemoryBytes:   req.MaxMemoryBytes,
+		MaxTableElements: req.MaxTableElements,
+		MaxInstances:     req.MaxInstances,
+		MaxMemories:      req.MaxMemories,
+		PreopenedDir:     preopenedDir,
+		Args:             req.Args,
+		Env:              env,
+		Secrets:          encryptedSecrets,
+		WorkspaceMode:    workspaceMode,
+		WorkspaceQuota:   req.WorkspaceQuota,
+		Egress: models.EgressRules{
+			Hosts: req.EgressHosts,
+			CIDRs: req.EgressCIDRs,
+			Ports: req.EgressPorts,
+		},
+		HTTPTimeoutMs:    req.HTTPTimeoutMs,
+		HTTPMaxBodyBytes: req.HTTPMaxBodyBytes,
+		HTTPMaxRedirects: req.HTTPMaxRedirects,
+		Kind:             kind,
+		ListenPort:       req.ListenPort,
 	}
 
 	createdRecord, err := ds.deploymentRepo.Create(context, runtimeRecord)
 	if err != nil {
-		// Clean up the file if DB insertion fails
-		// Delete from S3 if DB insertion fails
-		err = ds.s3Storage.DeleteFile(context, key)
+		// Delete from S3 if DB insertion fails, unless the file is shared
+		if !isExisting {
+			if delErr := ds.s3Storage.DeleteFile(context, key); delErr != nil {
+				log.Printf("failed to delete %s after a failed insert: %v", key, delErr)
+			}
+		}
+		if errors.Is(err, repository.ErrListenPortTaken) {
+			return nil, &InvalidListenPortError{Port: req.ListenPort, Reason: "already used by another deployment"}
+		}
 		return nil, fmt.Errorf("failed to save runtime to database: %w", err)
 	}
 
-	return &schemas.DeployResponse{
-		ID:          createdRecord.ID.String(),
-		IsExisting:  false,
-		RuntimeType: createdRecord.RuntimeType,
-		Hash:        createdRecord.Hash,
-		CreatedAt:   createdRecord.CreatedAt,
-		UpdatedAt:   createdRecord.UpdatedAt,
-	}, nil
+	res := newDeployResponse(createdRecord)
+	res.IsExisting = isExisting
+	return res, nil
 }
 
 func (ds *deploymentService) GetDeploymentByID(context context.Context, id uuid.UUID) (*schemas.DeployResponse, error) {
@@ -135,28 +291,14 @@ func (ds *deploymentService) GetDeploymentByID(context context.Context, id uuid.
 	if err != nil {
 		return nil, err
 	}
-	return &schemas.DeployResponse{
-		ID:          runtimeRecord.ID.String(),
-		RuntimeType: runtimeRecord.RuntimeType,
-		Hash:        runtimeRecord.Hash,
-		S3FilePath:  runtimeRecord.S3FilePath,
-		CreatedAt:   runtimeRecord.CreatedAt,
-		UpdatedAt:   runtimeRecord.UpdatedAt,
-	}, nil
+	return newDeployResponse(runtimeRecord), nil
 }
 func (ds *deploymentService) GetDeploymentByHash(context context.Context, hash string) (*schemas.DeployResponse, error) {
 	runtimeRecord, err := ds.deploymentRepo.FindByHash(context, hash)
 	if err != nil {
 		return nil, err
 	}
-	return &schemas.DeployResponse{
-		ID:          runtimeRecord.ID.String(),
-		RuntimeType: runtimeRecord.RuntimeType,
-		Hash:        runtimeRecord.Hash,
-		S3FilePath:  runtimeRecord.S3FilePath,
-		CreatedAt:   runtimeRecord.CreatedAt,
-		UpdatedAt:   runtimeRecord.UpdatedAt,
-	}, nil
+	return newDeployResponse(runtimeRecord), nil
 }
 func (ds *deploymentService) GetDeploymentFileContentByUUID(context context.Context, id uuid.UUID) ([]byte, error) {
 	res, err := ds.deploymentRepo.FindByID(context, id)
@@ -172,14 +314,7 @@ func (ds *deploymentService) ListAllDeployments(context context.Context) ([]*sch
 		return nil, err
 	}
 	for _, record := range records {
-		res = append(res, &schemas.DeployResponse{
-			ID:          record.ID.String(),
-			RuntimeType: record.RuntimeType,
-			Hash:        record.Hash,
-			S3FilePath:  record.S3FilePath,
-			CreatedAt:   record.CreatedAt,
-			UpdatedAt:   record.UpdatedAt,
-		})
+		res = append(res, newDeployResponse(record))
 	}
 	return res, nil
 }
@@ -191,6 +326,126 @@ func (ds *deploymentService) GetDeploymentFileContentByHash(context context.Cont
 	return ds.s3Storage.DownloadFile(context, res.S3FilePath)
 }
 
+// GetDeploymentEnv returns the guest environment with secrets decrypted
+func (ds *deploymentService) GetDeploymentEnv(context context.Context, id uuid.UUID) (map[string]string, error) {
+	record, err := ds.deploymentRepo.FindByID(context, id)
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string, len(record.Env)+len(record.Secrets))
+	for name, value := range record.Env {
+		env[name] = value
+	}
+	for name, encrypted := range record.Secrets {
+		value, err := ds.cipher.Decrypt(encrypted, secretContext(id, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// SetHostSecret encrypts value, binding it to the deployment and name, and
+// stores it
+func (ds *deploymentService) SetHostSecret(context context.Context, id uuid.UUID, name, value string) error {
+	if err := validateHostSecret(name, value); err != nil {
+		return err
+	}
+	if ds.cipher == nil {
+		return fmt.Errorf("secrets require an encryption key: %w", secrets.ErrNoKey)
+	}
+	encrypted, err := ds.cipher.Encrypt(value, hostSecretContext(id, name))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret %s: %w", name, err)
+	}
+	return ds.deploymentRepo.SetHostSecret(context, id, name, encrypted)
+}
+
+func (ds *deploymentService) DeleteHostSecret(context context.Context, id uuid.UUID, name string) (bool, error) {
+	return ds.deploymentRepo.DeleteHostSecret(context, id, name)
+}
+
+func (ds *deploymentService) GetHostSecrets(context context.Context, id uuid.UUID) (map[string][]byte, error) {
+	record, err := ds.deploymentRepo.FindByID(context, id)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(record.HostSecrets))
+	for name, encrypted := range record.HostSecrets {
+		value, err := ds.cipher.Decrypt(encrypted, hostSecretContext(id, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+		}
+		values[name] = []byte(value)
+	}
+	return values, nil
+}
+
+func (ds *deploymentService) DeleteDeployment(context context.Context, id uuid.UUID) error {
+	record, err := ds.deploymentRepo.FindByID(context, id)
+	if err != nil {
+		return err
+	}
+	// Files are shared by every deployment with the same hash. Holding its
+	// lock keeps CreateDeployment from reusing the file while it is deleted
+	unlock := ds.files.lock(record.Hash)
+	defer unlock()
+	if err := ds.deploymentRepo.Delete(context, id); err != nil {
+		return err
+	}
+	ds.hooksMu.Lock()
+	hooks := ds.deleteHooks
+	ds.hooksMu.Unlock()
+	for _, fn := range hooks {
+		fn(id)
+	}
+	if _, err := ds.deploymentRepo.FindByHash(context, record.Hash); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ds.s3Storage.DeleteFile(context, record.S3FilePath)
+	}
+	return nil
+}
+
+func (ds *deploymentService) OnDelete(fn func(id uuid.UUID)) {
+	ds.hooksMu.Lock()
+	defer ds.hooksMu.Unlock()
+	ds.deleteHooks = append(ds.deleteHooks, fn)
+}
+
+// newDeployResponse maps a record to its API representation. Secret values
+// are left out; only their names are reported.
+func newDeployResponse(record *models.Runtime) *schemas.DeployResponse {
+	return &schemas.DeployResponse{
+		ID:               record.ID.String(),
+		RuntimeType:      record.RuntimeType,
+		Hash:             record.Hash,
+		S3FilePath:       record.S3FilePath,
+		TimeoutMs:        record.TimeoutMs,
+		FuelBudget:       record.FuelBudget,
+		MaxMemoryBytes:   record.MaxMemoryBytes,
+		MaxTableElements: record.MaxTableElements,
+		MaxInstances:     record.MaxInstances,
+		MaxMemories:      record.MaxMemories,
+		PreopenedDir:     record.PreopenedDir,
+		Args:             record.Args,
+		Env:              record.Env,
+		SecretKeys:       sortedKeys(record.Secrets),
+		HostSecretKeys:   sortedKeys(record.HostSecrets),
+		WorkspaceMode:    record.WorkspaceMode,
+		WorkspaceQuota:   record.WorkspaceQuota,
+		EgressHosts:      record.Egress.Hosts,
+		EgressCIDRs:      record.Egress.CIDRs,
+		EgressPorts:      record.Egress.Ports,
+		HTTPTimeoutMs:    record.HTTPTimeoutMs,
+		HTTPMaxBodyBytes: record.HTTPMaxBodyBytes,
+		HTTPMaxRedirects: record.HTTPMaxRedirects,
+		Kind:             record.Kind,
+		ListenPort:       record.ListenPort,
+		CreatedAt:        record.CreatedAt,
+		UpdatedAt:        record.UpdatedAt,
+	}
+}
+
 // getFileExtensionForRuntimeType returns the expected file extension for a given runtime type
 func getFileExtensionForRuntimeType(runtimeType string) string {
 	switch runtimeType {
@@ -211,3 +466,214 @@ type InvalidRuntimeTypeError struct {
 func (e *InvalidRuntimeTypeError) Error() string {
 	return "Invalid runtime type: " + e.RuntimeType
 }
+
+// InvalidEnvError represents an error for a malformed environment variable
+type InvalidEnvError struct {
+	Entry  string
+	Reason string
+}
+
+func (e *InvalidEnvError) Error() string {
+	return fmt.Sprintf("Invalid environment variable %q: %s", e.Entry, e.Reason)
+}
+
+// InvalidSecretError represents an error for a host secret that can't be
+// stored
+type InvalidSecretError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidSecretError) Error() string {
+	return fmt.Sprintf("Invalid secret %q: %s", e.Name, e.Reason)
+}
+
+// InvalidPreopenedDirError represents an error for a preopened directory the
+// deployment may not use
+type InvalidPreopenedDirError struct {
+	Dir string
+	Err error
+}
+
+func (e *InvalidPreopenedDirError) Error() string {
+	return fmt.Sprintf("Invalid preopened directory %q: %v", e.Dir, e.Err)
+}
+
+func (e *InvalidPreopenedDirError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedOptionError represents an option the runtime type doesn't accept
+type UnsupportedOptionError struct {
+	Option      string
+	RuntimeType string
+}
+
+func (e *UnsupportedOptionError) Error() string {
+	return fmt.Sprintf("%s is not supported for %s deployments", e.Option, e.RuntimeType)
+}
+
+// InvalidWorkspaceModeError represents an error for an unknown workspace mode
+type InvalidWorkspaceModeError struct {
+	Mode string
+}
+
+func (e *InvalidWorkspaceModeError) Error() string {
+	return fmt.Sprintf("Invalid workspace mode %q: expected %s, %s or %s", e.Mode, runtime.WorkspaceNone, runtime.WorkspaceEphemeral, runtime.WorkspacePersistent)
+}
+
+// InvalidEgressPolicyError represents an error for malformed egress rules
+type InvalidEgressPolicyError struct {
+	Err error
+}
+
+func (e *InvalidEgressPolicyError) Error() string {
+	return fmt.Sprintf("Invalid egress policy: %v", e.Err)
+}
+
+func (e *InvalidEgressPolicyError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidDeploymentKindError represents an error for an unknown deployment kind
+type InvalidDeploymentKindError struct {
+	Kind string
+}
+
+func (e *InvalidDeploymentKindError) Error() string {
+	return fmt.Sprintf("Invalid deployment kind %q: expected %s or %s", e.Kind, DeploymentKindHTTP, DeploymentKindTCP)
+}
+
+// InvalidListenPortError represents an error for a port a TCP deployment
+// may not listen on
+type InvalidListenPortError struct {
+	Port   int
+	Reason string
+}
+
+func (e *InvalidListenPortError) Error() string {
+	return fmt.Sprintf("Invalid listen port %d: %s", e.Port, e.Reason)
+}
+
+// validateWorkspaceMode checks the requested workspace lifetime, defaulting
+// to an ephemeral workspace
+func validateWorkspaceMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return runtime.WorkspaceEphemeral, nil
+	case runtime.WorkspaceNone, runtime.WorkspaceEphemeral, runtime.WorkspacePersistent:
+		return mode, nil
+	default:
+		return "", &InvalidWorkspaceModeError{Mode: mode}
+	}
+}
+
+// validateKind checks the requested deployment kind and its listen port,
+// defaulting to an HTTP deployment
+func (ds *deploymentService) validateKind(req schemas.DeployRequest) (string, error) {
+	switch req.Kind {
+	case "", DeploymentKindHTTP:
+		if req.ListenPort != 0 {
+			return "", &UnsupportedOptionError{Option: "listen_port", RuntimeType: DeploymentKindHTTP}
+		}
+		return DeploymentKindHTTP, nil
+	case DeploymentKindTCP:
+	default:
+		return "", &InvalidDeploymentKindError{Kind: req.Kind}
+	}
+	if req.RuntimeType != "wasm" {
+		return "", &UnsupportedOptionError{Option: "kind tcp", RuntimeType: req.RuntimeType}
+	}
+	if req.ListenPort < ds.config.TCPPortMin || req.ListenPort > ds.config.TCPPortMax {
+		return "", &InvalidListenPortError{
+			Port:   req.ListenPort,
+			Reason: fmt.Sprintf("expected a port between %d and %d", ds.config.TCPPortMin, ds.config.TCPPortMax),
+		}
+	}
+	return DeploymentKindTCP, nil
+}
+
+// validateWasiOptions checks the WASI arguments and preopened directory of a
+// request and returns the directory normalized relative to the sandbox root
+func (ds *deploymentService) validateWasiOptions(req schemas.DeployRequest) (string, error) {
+	if req.RuntimeType != "wasm" {
+		if req.PreopenedDir != "" {
+			return "", &UnsupportedOptionError{Option: "preopened_dir", RuntimeType: req.RuntimeType}
+		}
+		if len(req.Args) > 0 {
+			return "", &UnsupportedOptionError{Option: "args", RuntimeType: req.RuntimeType}
+		}
+		return "", nil
+	}
+	if req.PreopenedDir == "" {
+		return "", nil
+	}
+	if ds.config.SandboxRoot == "" {
+		return "", &InvalidPreopenedDirError{Dir: req.PreopenedDir, Err: runtime.ErrNoSandboxRoot}
+	}
+	dir, err := runtime.CleanSandboxPath(req.PreopenedDir)
+	if err != nil {
+		return "", &InvalidPreopenedDirError{Dir: req.PreopenedDir, Err: err}
+	}
+	return dir, nil
+}
+
+// parseEnv parses KEY=VALUE entries into a map
+func parseEnv(entries []string) (models.StringMap, error) {
+	env := make(models.StringMap, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		switch {
+		case !ok:
+			return nil, &InvalidEnvError{Entry: entry, Reason: "expected KEY=VALUE"}
+		case name == "":
+			return nil, &InvalidEnvError{Entry: entry, Reason: "empty name"}
+		case strings.ContainsRune(entry, 0):
+			return nil, &InvalidEnvError{Entry: name, Reason: "contains a NUL byte"}
+		}
+		if _, dup := env[name]; dup {
+			return nil, &InvalidEnvError{Entry: name, Reason: "defined more than once"}
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// secretContext binds an encrypted secret to its deployment and name
+func secretContext(id uuid.UUID, name string) string {
+	return id.String() + "/" + name
+}
+
+// hostSecretContext binds an encrypted host secret to its deployment and
+// name. Its prefix keeps it apart from every secretContext, so an
+// environment secret's ciphertext can't pass for a host secret.
+func hostSecretContext(id uuid.UUID, name string) string {
+	return "host:" + id.String() + "/" + name
+}
+
+// sortedKeys returns the names in m, sorted
+func sortedKeys(m models.StringMap) []string {
+	keys := make([]string, 0, len(m))
+	for name := range m {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateHostSecret checks the name and size of a host secret. Names are
+// limited to letters, digits, '_', '-' and '.'.
+func validateHostSecret(name, value string) error {
+	if name == "" || len(name) > 128 {
+		return &InvalidSecretError{Name: name, Reason: "name must be 1 to 128 characters"}
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			return &InvalidSecretError{Name: name, Reason: "name may only contain letters, digits, '_', '-' and '.'"}
+		}
+	}
+	if len(value) > maxHostSecretBytes {
+		return &InvalidSecretError{Name: name, Reason: fmt.Sprintf("value larger than %d bytes", maxHostSecretBytes)}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+	"github.com/ignis-runtime/ignis-wasmtime/types"
+	"gorm.io/gorm"
+)
+
+// Invoke implements host_functions.Invoker, running the target of a guest's
+// host_invoke through ExecuteDeployment without leaving the process. The
+// target is a deployment UUID. ctx is the caller's execution context, so the
+// target never runs past the caller's deadline; a call nesting deeper than
+// InvokeMaxDepth, or reaching a deployment already in the call chain, is
+// refused.
+func (s *runService) Invoke(ctx context.Context, target string, request *types.FDRequest) (*types.FDResponse, error) {
+	id, err := uuid.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a deployment UUID", host_functions.ErrInvokeNotFound, target)
+	}
+	if err := host_functions.CheckCallChain(ctx, id, s.config.InvokeMaxDepth); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.ExecuteDeployment(ctx, id, request)
+	switch {
+	case err == nil:
+		return resp, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("%w: %s", host_functions.ErrInvokeNotFound, id)
+	case errors.Is(err, runtime.ErrExecutionTimeout):
+		return nil, fmt.Errorf("%w: %v", host_functions.ErrInvokeTimeout, err)
+	default:
+		return nil, err
+	}
+}
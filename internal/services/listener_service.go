@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/schemas"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/config"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
+)
+
+var (
+	tcpConnections = metrics.NewCounterVec(
+		"ignis_tcp_connections_total",
+		"Connections accepted for TCP deployments, by outcome.",
+		"deployment_id", "outcome",
+	)
+	tcpActiveConnections = metrics.NewGaugeVec(
+		"ignis_tcp_active_connections",
+		"Connections of TCP deployments being served by a guest.",
+		"deployment_id",
+	)
+)
+
+// ListenerService runs the host-managed listeners of TCP deployments. Each
+// accepted connection is served by a fresh guest instance.
+type ListenerService interface {
+	// Start listens for every stored TCP deployment
+	Start(ctx context.Context) error
+	// Listen starts accepting connections for a TCP deployment. A deployment
+	// that already listens is left alone.
+	Listen(deployment *schemas.DeployResponse) error
+	// Close stops accepting connections and waits for the guests serving
+	// accepted ones to exit
+	Close() error
+}
+
+// listenerService implements the ListenerService interface
+type listenerService struct {
+	runService        RunService
+	deploymentService DeploymentService
+	config            *config.Config
+
+	// ctx bounds the guests serving connections and is cancelled by Close
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[uuid.UUID]net.Listener
+	wg        sync.WaitGroup
+}
+
+// NewListenerService creates a ListenerService. Nothing listens until Start
+// or Listen is called.
+func NewListenerService(runService RunService, deploymentService DeploymentService, config *config.Config) ListenerService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &listenerService{
+		runService:        runService,
+		deploymentService: deploymentService,
+		config:            config,
+		ctx:               ctx,
+		cancel:            cancel,
+		listeners:         make(map[uuid.UUID]net.Listener),
+	}
+}
+
+// Start listens for every stored TCP deployment. A port that can't be bound
+// is logged and skipped, so one deployment can't keep the others down.
+func (s *listenerService) Start(ctx context.Context) error {
+	deployments, err := s.deploymentService.ListAllDeployments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deployment := range deployments {
+		if deployment.Kind != DeploymentKindTCP {
+			continue
+		}
+		if err := s.Listen(deployment); err != nil {
+			log.Printf("listen %s: %v", deployment.ID, err)
+		}
+	}
+	return nil
+}
+
+// Listen binds the deployment's port on the configured host and serves its
+// connections in the background
+func (s *listenerService) Listen(deployment *schemas.DeployResponse) error {
+	if deployment.Kind != DeploymentKindTCP {
+		return fmt.Errorf("deployment %s is not a TCP deployment", deployment.ID)
+	}
+	id, err := uuid.Parse(deployment.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return errors.New("listener service is closed")
+	}
+	if _, ok := s.listeners[id]; ok {
+		return nil
+	}
+	addr := net.JoinHostPort(s.config.TCPListenHost, strconv.Itoa(deployment.ListenPort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.listeners[id] = listener
+	log.Printf("listen %s: accepting connections on %s", id, listener.Addr())
+
+	s.wg.Add(1)
+	go s.accept(id, listener)
+	return nil
+}
+
+// accept hands each connection to a guest until the listener is closed.
+// Connections beyond the configured maximum are closed right away. Accept
+// failures that may pass, such as running out of file descriptors, are
+// retried with a capped backoff, like net/http does; any other failure stops
+// the listener and removes it, so the deployment can listen again.
+func (s *listenerService) accept(id uuid.UUID, listener net.Listener) {
+	defer s.wg.Done()
+	slots := make(chan struct{}, max(s.config.TCPMaxConnections, 1))
+	var delay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if acceptRetryable(err) {
+				delay = min(max(2*delay, minAcceptDelay), maxAcceptDelay)
+				log.Printf("listen %s: accept failed: %v; retrying in %v", id, err, delay)
+				select {
+				case <-time.After(delay):
+					continue
+				case <-s.ctx.Done():
+					return
+				}
+			}
+			log.Printf("listen %s: accept failed: %v; no longer listening", id, err)
+			s.remove(id, listener)
+			return
+		}
+		delay = 0
+		select {
+		case slots <- struct{}{}:
+		default:
+			tcpConnections.Inc(id.String(), "rejected")
+			conn.Close()
+			continue
+		}
+
+		tcpConnections.Inc(id.String(), "accepted")
+		tcpActiveConnections.Add(1, id.String())
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				tcpActiveConnections.Add(-1, id.String())
+				<-slots
+			}()
+			if err := s.runService.ServeConnection(s.ctx, id, conn); err != nil {
+				log.Printf("conn %s from %s: %v", id, conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// Delays between retries of a failed Accept
+const (
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
+)
+
+// acceptRetryable reports whether a failed Accept may succeed later
+func acceptRetryable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout() ||
+		errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.ENOBUFS) || errors.Is(err, syscall.ENOMEM) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// remove closes listener and forgets it, unless the deployment listens on
+// another one by now
+func (s *listenerService) remove(id uuid.UUID, listener net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listeners[id] == listener {
+		delete(s.listeners, id)
+	}
+	listener.Close()
+}
+
+// Close stops every listener, interrupts the guests still serving
+// connections and waits for them to exit
+func (s *listenerService) Close() error {
+	s.mu.Lock()
+	s.cancel()
+	var errs []error
+	for id, listener := range s.listeners {
+		if err := listener.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.listeners, id)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
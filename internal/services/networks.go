@@ -0,0 +1,73 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+)
+
+// networkCache keeps the *host_functions.Network of the deployments that ran
+// most recently, so their HTTP connections are pooled across executions. It
+// is safe for concurrent use.
+type networkCache struct {
+	mu   sync.Mutex
+	size int
+	// order lists the cached deployments, most recently used first.
+	order    *list.List
+	networks map[uuid.UUID]*list.Element
+}
+
+type networkEntry struct {
+	deploymentID uuid.UUID
+	network      *host_functions.Network
+}
+
+// newNetworkCache creates a cache of at most size networks. Once full, the
+// network used least recently is dropped to make room for another.
+func newNetworkCache(size int) *networkCache {
+	return &networkCache{
+		size:     size,
+		order:    list.New(),
+		networks: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+// getOrAdd returns the network cached for the deployment, or caches and
+// returns the one built by create.
+func (c *networkCache) getOrAdd(id uuid.UUID, create func() (*host_functions.Network, error)) (*host_functions.Network, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.networks[id]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*networkEntry).network, nil
+	}
+	network, err := create()
+	if err != nil || c.size <= 0 {
+		return network, err
+	}
+	c.networks[id] = c.order.PushFront(&networkEntry{deploymentID: id, network: network})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return network, nil
+}
+
+// forget drops the deployment's network, for when it is deleted.
+func (c *networkCache) forget(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.networks[id]; ok {
+		c.remove(elem)
+	}
+}
+
+// remove drops elem and closes its network's idle connections. Executions
+// still using the network keep working; its client is simply no longer
+// shared.
+func (c *networkCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*networkEntry)
+	delete(c.networks, entry.deploymentID)
+	entry.network.Close()
+}
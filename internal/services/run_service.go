@@ -2,56 +2,268 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bytecodealliance/wasmtime-go/v41"
 	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/api/rest/v1/schemas"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/cache"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/config"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/kv"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/logs"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/js"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/wasm"
 	"github.com/ignis-runtime/ignis-wasmtime/types"
 	"google.golang.org/protobuf/proto"
 )
 
+// FuelConsumedHeader is the response header reporting the fuel used by an invocation
+const FuelConsumedHeader = "X-Ignis-Fuel-Consumed"
+
+// moduleLoadTimeout bounds fetching and compiling a module missing from the
+// in-process cache.
+const moduleLoadTimeout = 2 * time.Minute
+
 // RunService defines the interface for running deployments
 type RunService interface {
 	ExecuteDeployment(ctx context.Context, id uuid.UUID, request *types.FDRequest) (*types.FDResponse, error)
+	// ServeConnection runs a TCP deployment on one accepted connection
+	ServeConnection(ctx context.Context, id uuid.UUID, conn net.Conn) error
+	// RecentLogs returns up to limit of the deployment's latest guest logs, newest first
+	RecentLogs(id uuid.UUID, limit int) []logs.Entry
 }
 
 // runService implements the RunService interface
 type runService struct {
 	cache             *cache.RedisCache
 	deploymentService DeploymentService
+	config            *config.Config
+
+	// engine and linker are shared by every execution; the engine's epoch is
+	// advanced by ticker.
+	engine  *wasmtime.Engine
+	linker  *wasmtime.Linker
+	ticker  *runtime.EpochTicker
+	modules *runtime.ModuleCache
+	logs    *logs.Store
+	// kv holds the deployments' key-value namespaces, next to the module
+	// cache in Redis.
+	kv kv.Store
+	// secrets hands the deployments' host secrets to guests, auditing their
+	// reads.
+	secrets host_functions.SecretStore
+	// logLimit bounds each execution's host_log records
+	logLimit host_functions.LogLimit
+	// metrics aggregates the samples guests record with host_metric, by
+	// deployment.
+	metrics *metrics.Partitioned
+
+	// networks holds the *host_functions.Network of the deployments that ran
+	// most recently, so their HTTP connections are pooled across executions.
+	networks *networkCache
 }
 
 // NewRunService creates a new RunService instance
-func NewRunService(cache *cache.RedisCache, deploymentService DeploymentService) RunService {
-	return &runService{
+func NewRunService(cache *cache.RedisCache, deploymentService DeploymentService, config *config.Config) (RunService, error) {
+	engine := runtime.NewEngine(runtime.PoolingConfig{
+		Enabled:       config.PoolingAllocator,
+		MaxInstances:  config.PoolingMaxInstances,
+		MemoryPages:   config.PoolingMemoryPages,
+		TableElements: config.PoolingTableElements,
+	})
+	linker, err := runtime.NewLinker(engine)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build linker: %w", err)
+	}
+	s := &runService{
 		cache:             cache,
 		deploymentService: deploymentService,
+		config:            config,
+		engine:            engine,
+		linker:            linker,
+		ticker:            runtime.StartEpochTicker(engine),
+		modules:           runtime.NewModuleCache(config.ModuleCacheBytes),
+		logs:              logs.NewStore(config.GuestLogHistory, config.GuestLogDeployments),
+		kv:                cache.KV(config.KVMaxKeys, config.KVMaxValueBytes),
+		secrets:           auditedSecrets{deploymentService: deploymentService},
+		logLimit: host_functions.LogLimit{
+			PerSecond: float64(config.GuestLogRate),
+			Burst:     config.GuestLogBurst,
+			MaxBytes:  config.GuestLogMaxBytes,
+		},
+		metrics: metrics.NewPartitioned(
+			"ignis_guest_",
+			"Recorded by deployments with host_metric.",
+			"deployment_id",
+			config.GuestMetricMaxSeries,
+		),
+		networks: newNetworkCache(config.NetworkCacheDeployments),
 	}
+	deploymentService.OnDelete(s.forget)
+	return s, nil
+}
+
+// forget drops what the service keeps in memory for a deleted deployment.
+func (s *runService) forget(id uuid.UUID) {
+	s.networks.forget(id)
+	s.logs.Forget(id)
+	s.metrics.Drop(id.String())
 }
 
+// ErrTCPDeployment is returned when a TCP deployment is run with an HTTP
+// request; it only serves connections accepted on its listen port.
+var ErrTCPDeployment = errors.New("deployment serves TCP connections, not HTTP requests")
+
 // ExecuteDeployment executes a deployment by UUID with the given HTTP request context
 func (s *runService) ExecuteDeployment(ctx context.Context, id uuid.UUID, request *types.FDRequest) (*types.FDResponse, error) {
 	deployment, err := s.deploymentService.GetDeploymentByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get deployment: %w", err)
 	}
+	if deployment.Kind == DeploymentKindTCP {
+		return nil, ErrTCPDeployment
+	}
+
+	rt, release, err := s.newRuntime(ctx, id, deployment, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer rt.Close(ctx)
+
+	// Create FDRequest from HTTP request context
+	fdRequest := &types.FDRequest{
+		Method:        request.Method,
+		Body:          request.Body,
+		ContentLength: request.ContentLength,
+		Host:          request.Host,
+		RemoteAddr:    request.RemoteAddr,
+		RequestUri:    request.RequestUri,
+		Header:        request.Header,
+	}
+
+	reqBytes, err := proto.Marshal(fdRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respBytes, err := rt.Execute(ctx, reqBytes)
+	stats := rt.Stats()
+	log.Printf("run %s: fuel consumed %d/%d", id, stats.FuelConsumed, stats.FuelBudget)
+	s.recordLogs(ctx, id, rt.Logs())
+	if err != nil {
+		return nil, fmt.Errorf("runtime execution failed: %w", err)
+	}
+
+	var fdResponse types.FDResponse
+	err = proto.Unmarshal(respBytes, &fdResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if fdResponse.Header == nil {
+		fdResponse.Header = make(map[string]*types.HeaderFields)
+	}
+	fdResponse.Header[FuelConsumedHeader] = &types.HeaderFields{
+		Fields: []string{strconv.FormatUint(stats.FuelConsumed, 10)},
+	}
+
+	return &fdResponse, nil
+}
+
+// ServeConnection hands an inbound connection of a TCP deployment to a fresh
+// guest instance and returns once the guest exits. The connection is closed
+// by then.
+func (s *runService) ServeConnection(ctx context.Context, id uuid.UUID, conn net.Conn) error {
+	defer conn.Close()
+	deployment, err := s.deploymentService.GetDeploymentByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get deployment: %w", err)
+	}
+
+	rt, release, err := s.newRuntime(ctx, id, deployment, conn)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer rt.Close(ctx)
+
+	_, err = rt.Execute(ctx, []byte(nil))
+	stats := rt.Stats()
+	log.Printf("conn %s from %s: fuel consumed %d/%d", id, conn.RemoteAddr(), stats.FuelConsumed, stats.FuelBudget)
+	s.recordLogs(ctx, id, rt.Logs())
+	if err != nil {
+		return fmt.Errorf("runtime execution failed: %w", err)
+	}
+	return nil
+}
+
+// newRuntime instantiates the deployment's runtime, serving conn if it is
+// not nil. release removes the workspace opened for it and must be called
+// once the runtime is closed.
+func (s *runService) newRuntime(ctx context.Context, id uuid.UUID, deployment *schemas.DeployResponse, conn net.Conn) (_ runtime.Runtime, release func(), err error) {
+	timeout := s.config.ExecutionTimeout
+	if deployment.TimeoutMs > 0 {
+		timeout = time.Duration(deployment.TimeoutMs) * time.Millisecond
+	}
+	fuelBudget := s.config.FuelBudget
+	if deployment.FuelBudget > 0 {
+		fuelBudget = uint64(deployment.FuelBudget)
+	}
+	limits := s.resourceLimits(deployment)
+	env, err := s.deploymentService.GetDeploymentEnv(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get deployment environment: %w", err)
+	}
+
+	network, err := s.network(id, deployment)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	quota := s.config.WorkspaceQuotaBytes
+	if deployment.WorkspaceQuota > 0 {
+		quota = deployment.WorkspaceQuota
+	}
+	workspace, err := runtime.OpenWorkspace(s.config.WorkspaceRoot, id, deployment.WorkspaceMode, quota)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open workspace: %w", err)
+	}
+	release = func() {
+		if err := workspace.Close(); err != nil {
+			log.Printf("run %s: failed to remove workspace: %v", id, err)
+		}
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
 
 	var config runtime.RuntimeConfig
 
 	switch strings.ToLower(deployment.RuntimeType) {
 	case "js":
+		if conn != nil {
+			return nil, nil, fmt.Errorf("js deployments can't serve TCP connections")
+		}
+
 		// 1. Get/Compile QuickJS Engine
-		engineBytes, err := s.getSerializedModule(ctx, "qjs-serialized", func() ([]byte, error) {
+		qjsPre, err := s.getInstancePre(ctx, "qjs-serialized", func(context.Context) ([]byte, error) {
 			return js.QJSWasm, nil
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to get JS engine: %w", err)
+			return nil, nil, fmt.Errorf("failed to get JS engine: %w", err)
 		}
 
 		// 2. Get JS Script Source (Directly from cache or DB)
@@ -61,89 +273,185 @@ func (s *runService) ExecuteDeployment(ctx context.Context, id uuid.UUID, reques
 		} else {
 			jsFile, err = s.deploymentService.GetDeploymentFileContentByHash(ctx, deployment.Hash)
 			if err != nil {
-				return nil, fmt.Errorf("failed to get JS file content: %w", err)
+				return nil, nil, fmt.Errorf("failed to get JS file content: %w", err)
 			}
 			_ = s.cache.Set(ctx, deployment.Hash, &types.Module{Hash: deployment.Hash, Data: jsFile}, time.Hour*2)
 		}
 
-		config = js.NewRuntimeConfig(id).WithSerializedModule(engineBytes).WithJSFile(jsFile)
+		config = js.NewRuntimeConfig(id).WithInstancePre(qjsPre).WithJSFile(jsFile).WithTimeout(timeout).WithFuelBudget(fuelBudget).WithLimits(limits).WithMaxLogBytes(s.config.GuestLogMaxBytes).WithEnv(env).WithWorkspace(workspace).WithNetwork(network).WithKV(s.kv).WithSecrets(s.secrets).WithLogLimit(s.logLimit).WithMetrics(s.metrics).WithInvoker(s)
 
 	case "wasm":
+		preopenedDir := ""
+		if deployment.PreopenedDir != "" {
+			preopenedDir, err = runtime.SandboxPath(s.config.SandboxRoot, deployment.PreopenedDir)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to resolve preopened directory: %w", err)
+			}
+		}
+
 		// Get/Compile the specific WASM deployment
-		moduleBytes, err := s.getSerializedModule(ctx, deployment.Hash, func() ([]byte, error) {
+		pre, err := s.getInstancePre(ctx, deployment.Hash, func(ctx context.Context) ([]byte, error) {
 			return s.deploymentService.GetDeploymentFileContentByUUID(ctx, id)
 		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to get WASM module: %w", err)
+			return nil, nil, fmt.Errorf("failed to get WASM module: %w", err)
 		}
-		config = wasm.NewRuntimeConfig(id).WithSerializedModule(moduleBytes)
+		config = wasm.NewRuntimeConfig(id).WithInstancePre(pre).WithTimeout(timeout).WithFuelBudget(fuelBudget).WithLimits(limits).WithMaxLogBytes(s.config.GuestLogMaxBytes).WithEnv(env).WithArgs(deployment.Args).WithPreopenedDir(preopenedDir).WithWorkspace(workspace).WithNetwork(network).WithKV(s.kv).WithSecrets(s.secrets).WithLogLimit(s.logLimit).WithMetrics(s.metrics).WithInvoker(s).WithConnection(conn)
 
 	default:
-		return nil, fmt.Errorf("invalid runtime type: %s", deployment.RuntimeType)
+		return nil, nil, fmt.Errorf("invalid runtime type: %s", deployment.RuntimeType)
 	}
 
 	// Lifecycle execution
 	rt, err := config.Instantiate()
 	if err != nil {
-		return nil, fmt.Errorf("failed to instantiate runtime: %w", err)
+		return nil, nil, fmt.Errorf("failed to instantiate runtime: %w", err)
 	}
-	defer rt.Close(ctx)
+	return rt, release, nil
+}
 
-	// Create FDRequest from HTTP request context
-	fdRequest := &types.FDRequest{
-		Method:        request.Method,
-		Body:          request.Body,
-		ContentLength: request.ContentLength,
-		Host:          request.Host,
-		RemoteAddr:    request.RemoteAddr,
-		RequestUri:    request.RequestUri,
-		Header:        request.Header,
+// recordLogs attributes the guest's stderr to its deployment and request,
+// writes it to the server log and keeps it for the logs endpoint along with
+// the guest's host_log records, which reached the server log as they were
+// emitted
+func (s *runService) recordLogs(ctx context.Context, id uuid.UUID, execLog runtime.ExecutionLog) {
+	if len(execLog.Stderr) == 0 && len(execLog.Records) == 0 && execLog.RecordsDropped == 0 {
+		return
 	}
-
-	reqBytes, err := proto.Marshal(fdRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	entry := logs.Entry{
+		Time:           time.Now(),
+		DeploymentID:   id,
+		RequestID:      logs.RequestID(ctx),
+		Stderr:         string(execLog.Stderr),
+		Truncated:      execLog.Truncated,
+		RecordsDropped: execLog.RecordsDropped,
 	}
-
-	respBytes, err := rt.Execute(ctx, reqBytes)
-	if err != nil {
-		return nil, fmt.Errorf("runtime execution failed: %w", err)
+	for _, record := range execLog.Records {
+		fields := make(map[string]string, len(record.Fields))
+		for _, field := range record.Fields {
+			fields[field.Key] = field.Value
+		}
+		entry.Records = append(entry.Records, logs.Record{
+			Time:    record.Time,
+			Level:   strings.ToLower(record.Level.String()),
+			Message: record.Message,
+			Fields:  fields,
+		})
 	}
-
-	var fdResponse types.FDResponse
-	err = proto.Unmarshal(respBytes, &fdResponse)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if entry.Stderr != "" {
+		slog.Info("guest stderr",
+			"deployment_id", id,
+			"request_id", entry.RequestID,
+			"truncated", entry.Truncated,
+			"stderr", entry.Stderr,
+		)
 	}
+	if entry.RecordsDropped > 0 {
+		slog.Warn("guest log records dropped",
+			"deployment_id", id,
+			"request_id", entry.RequestID,
+			"dropped", entry.RecordsDropped,
+		)
+	}
+	s.logs.Append(entry)
+}
 
-	return &fdResponse, nil
+// RecentLogs returns up to limit of the deployment's latest guest logs, newest first
+func (s *runService) RecentLogs(id uuid.UUID, limit int) []logs.Entry {
+	return s.logs.Recent(id, limit)
+}
+
+// getInstancePre returns the pre-linked module for key, checking the in-process
+// cache before falling back to the serialized artifact cache. Concurrent
+// misses share one load, so it isn't tied to the request that started it.
+func (s *runService) getInstancePre(ctx context.Context, key string, loader func(context.Context) ([]byte, error)) (*runtime.InstancePre, error) {
+	return s.modules.GetOrLoad(key, func() (*runtime.InstancePre, int64, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), moduleLoadTimeout)
+		defer cancel()
+		module, size, err := s.loadModule(ctx, serializedModuleKey(key), func() ([]byte, error) {
+			return loader(ctx)
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		pre, err := runtime.NewInstancePre(s.engine, s.linker, module)
+		if err != nil {
+			return nil, 0, fmt.Errorf("link failed: %w", err)
+		}
+		return pre, size, nil
+	})
 }
 
-// getSerializedModule abstracts the "Check Cache -> Compile -> Store Cache" workflow
-func (s *runService) getSerializedModule(ctx context.Context, cacheKey string, loader func() ([]byte, error)) ([]byte, error) {
+// loadModule abstracts the "Check Cache -> Compile -> Store Cache" workflow
+// and returns the module along with its serialized size
+func (s *runService) loadModule(ctx context.Context, cacheKey string, loader func() ([]byte, error)) (*wasmtime.Module, int64, error) {
 	if cached, exists := s.cache.Get(ctx, cacheKey); exists {
-		return cached.Data, nil
+		module, err := wasmtime.NewModuleDeserialize(s.engine, cached.Data)
+		if err == nil {
+			return module, int64(len(cached.Data)), nil
+		}
+		// Unusable artifact: recompile and overwrite it below
 	}
 
 	// Cache miss: Load raw bytes
 	raw, err := loader()
 	if err != nil {
-		return nil, fmt.Errorf("loader failed: %w", err)
+		return nil, 0, fmt.Errorf("loader failed: %w", err)
 	}
 
-	// Compile and Serialize
-	engine := wasmtime.NewEngine()
-	module, err := wasmtime.NewModule(engine, raw)
+	// Compile with the shared engine and serialize for other instances
+	module, err := wasmtime.NewModule(s.engine, raw)
 	if err != nil {
-		return nil, fmt.Errorf("compilation failed: %w", err)
+		return nil, 0, fmt.Errorf("compilation failed: %w", err)
 	}
 
 	serialized, err := module.Serialize()
 	if err != nil {
-		return nil, fmt.Errorf("serialization failed: %w", err)
+		return nil, 0, fmt.Errorf("serialization failed: %w", err)
 	}
 
-	// Async cache update to avoid blocking
 	_ = s.cache.Set(ctx, cacheKey, &types.Module{Hash: cacheKey, Data: serialized}, time.Hour*2)
-	return serialized, nil
+	return module, int64(len(serialized)), nil
+}
+
+// resourceLimits overlays the deployment's store limits on the server defaults
+func (s *runService) resourceLimits(deployment *schemas.DeployResponse) runtime.ResourceLimits {
+	return runtime.ResourceLimits{
+		MaxMemoryBytes:   firstPositive(deployment.MaxMemoryBytes, s.config.MaxMemoryBytes),
+		MaxTableElements: firstPositive(deployment.MaxTableElements, s.config.MaxTableElements),
+		MaxInstances:     firstPositive(deployment.MaxInstances, s.config.MaxInstances),
+		MaxMemories:      firstPositive(deployment.MaxMemories, s.config.MaxMemories),
+	}
+}
+
+// network returns the deployment's egress policy and shared HTTP client,
+// building them on first use.
+func (s *runService) network(id uuid.UUID, deployment *schemas.DeployResponse) (*host_functions.Network, error) {
+	return s.networks.getOrAdd(id, func() (*host_functions.Network, error) {
+		egress, err := host_functions.NewEgressPolicy(deployment.EgressHosts, deployment.EgressCIDRs, deployment.EgressPorts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress policy: %w", err)
+		}
+		return host_functions.NewNetwork(egress, host_functions.HTTPLimits{
+			ConnectTimeout: s.config.HTTPConnectTimeout,
+			Timeout:        time.Duration(firstPositive(deployment.HTTPTimeoutMs*int64(time.Millisecond), int64(s.config.HTTPTimeout))),
+			MaxBodyBytes:   firstPositive(deployment.HTTPMaxBodyBytes, s.config.HTTPMaxBodyBytes),
+			MaxRedirects:   int(firstPositive(deployment.HTTPMaxRedirects, s.config.HTTPMaxRedirects)),
+		}), nil
+	})
+}
+
+func firstPositive(values ...int64) int64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+// serializedModuleKey namespaces serialized module cache entries by engine
+// configuration, since artifacts from a different configuration can't be loaded.
+func serializedModuleKey(key string) string {
+	return fmt.Sprintf("%s:%s", key, runtime.EngineConfigVersion)
 }
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/logs"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/metrics"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/runtime/host_functions"
+)
+
+var secretAccesses = metrics.NewCounterVec(
+	"ignis_secret_accesses_total",
+	"Host secrets read by guests, by outcome.",
+	"deployment_id", "outcome",
+)
+
+// auditedSecrets hands host secrets to guests through host_secret_get. The
+// reads of a session are logged once it ends, one record per secret with
+// the deployment, the request that caused the execution, the secret's name
+// and how often it was read, never its value.
+type auditedSecrets struct {
+	deploymentService DeploymentService
+}
+
+// LoadSecrets implements host_functions.SecretStore
+func (a auditedSecrets) LoadSecrets(ctx context.Context, deploymentID uuid.UUID) (map[string][]byte, error) {
+	values, err := a.deploymentService.GetHostSecrets(ctx, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", host_functions.ErrSecretsUnavailable, err)
+	}
+	return values, nil
+}
+
+// AuditSecrets implements host_functions.SecretStore
+func (a auditedSecrets) AuditSecrets(ctx context.Context, deploymentID uuid.UUID, reads []host_functions.SecretRead) {
+	for _, read := range reads {
+		outcome := "granted"
+		switch {
+		case read.Err == nil:
+		case errors.Is(read.Err, host_functions.ErrSecretNotFound):
+			outcome = "not_found"
+		default:
+			outcome = "failed"
+		}
+		secretAccesses.Add(float64(read.Count), deploymentID.String(), outcome)
+		slog.Info("secret access",
+			"deployment_id", deploymentID,
+			"request_id", logs.RequestID(ctx),
+			"secret", read.Name,
+			"outcome", outcome,
+			"reads", read.Count,
+		)
+	}
+}
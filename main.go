@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/ignis-runtime/ignis-wasmtime/internal/config"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/models"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/repository"
+	"github.com/ignis-runtime/ignis-wasmtime/internal/secrets"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/services"
 	"github.com/ignis-runtime/ignis-wasmtime/internal/storage"
 	swaggerFiles "github.com/swaggo/files"
@@ -86,15 +88,35 @@ func main() {
 	addr := ":8080"
 
 	// Initialize deploymentService
-	deployService := services.NewDeploymentService(deploymentRepository, s3Storage, cfg)
-	runService := services.NewRunService(redisCache, deployService)
+	var secretCipher *secrets.Cipher
+	if cfg.EncryptionKey != "" {
+		secretCipher, err = secrets.NewCipher(cfg.EncryptionKey)
+		if err != nil {
+			log.Fatalf("Invalid ENCRYPTION_KEY: %v", err)
+		}
+	} else {
+		log.Println("ENCRYPTION_KEY not set, deployments with secrets will be rejected")
+	}
+
+	deployService := services.NewDeploymentService(deploymentRepository, s3Storage, cfg, secretCipher)
+	runService, err := services.NewRunService(redisCache, deployService, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize run service: %v", err)
+	}
+
+	// Bind the listeners of TCP deployments
+	listenerService := services.NewListenerService(runService, deployService, cfg)
+	if err := listenerService.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start TCP listeners: %v", err)
+	}
+	defer listenerService.Close()
 
 	srv := server.NewServer(addr, redisCache, deployService)
 
 	// Register Swagger documentation and UI
 	srv.Engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	routes.RegisterRoutes(srv, runService, deployService, redisCache)
+	routes.RegisterRoutes(srv, runService, deployService, listenerService, redisCache)
 
 	log.Printf("Starting Gin HTTP server on port %s", addr)
 	if err := srv.Run(); err != nil {
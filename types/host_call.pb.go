@@ -0,0 +1,897 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: types/host_call.proto
+
+package types
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type HostHTTPRequest struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Method        string                   `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Url           string                   `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Headers       map[string]*HeaderFields `protobuf:"bytes,3,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body          []byte                   `protobuf:"bytes,4,opt,name=body,proto3" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostHTTPRequest) Reset() {
+	*x = HostHTTPRequest{}
+	mi := &file_types_host_call_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostHTTPRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostHTTPRequest) ProtoMessage() {}
+
+func (x *HostHTTPRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostHTTPRequest.ProtoReflect.Descriptor instead.
+func (*HostHTTPRequest) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *HostHTTPRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *HostHTTPRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *HostHTTPRequest) GetHeaders() map[string]*HeaderFields {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *HostHTTPRequest) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+type HostHTTPResponse struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	StatusCode    int32                    `protobuf:"varint,1,opt,name=status_code,json=statusCode,proto3" json:"status_code,omitempty"`
+	Headers       map[string]*HeaderFields `protobuf:"bytes,2,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Body          []byte                   `protobuf:"bytes,3,opt,name=body,proto3" json:"body,omitempty"`
+	Error         string                   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Code          string                   `protobuf:"bytes,5,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostHTTPResponse) Reset() {
+	*x = HostHTTPResponse{}
+	mi := &file_types_host_call_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostHTTPResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostHTTPResponse) ProtoMessage() {}
+
+func (x *HostHTTPResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostHTTPResponse.ProtoReflect.Descriptor instead.
+func (*HostHTTPResponse) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HostHTTPResponse) GetStatusCode() int32 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *HostHTTPResponse) GetHeaders() map[string]*HeaderFields {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *HostHTTPResponse) GetBody() []byte {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *HostHTTPResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *HostHTTPResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+type HostSocketRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operation     string                 `protobuf:"bytes,1,opt,name=operation,proto3" json:"operation,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Fd            int32                  `protobuf:"varint,3,opt,name=fd,proto3" json:"fd,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Size          int32                  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Network       string                 `protobuf:"bytes,6,opt,name=network,proto3" json:"network,omitempty"`
+	TimeoutMs     int32                  `protobuf:"varint,7,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostSocketRequest) Reset() {
+	*x = HostSocketRequest{}
+	mi := &file_types_host_call_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostSocketRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostSocketRequest) ProtoMessage() {}
+
+func (x *HostSocketRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostSocketRequest.ProtoReflect.Descriptor instead.
+func (*HostSocketRequest) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HostSocketRequest) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *HostSocketRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *HostSocketRequest) GetFd() int32 {
+	if x != nil {
+		return x.Fd
+	}
+	return 0
+}
+
+func (x *HostSocketRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *HostSocketRequest) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *HostSocketRequest) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *HostSocketRequest) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type HostSocketResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         string                 `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	Code          string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Fd            int32                  `protobuf:"varint,3,opt,name=fd,proto3" json:"fd,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	BytesRead     int32                  `protobuf:"varint,5,opt,name=bytes_read,json=bytesRead,proto3" json:"bytes_read,omitempty"`
+	BytesSent     int32                  `protobuf:"varint,6,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	Address       string                 `protobuf:"bytes,7,opt,name=address,proto3" json:"address,omitempty"`
+	Addresses     []string               `protobuf:"bytes,8,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostSocketResponse) Reset() {
+	*x = HostSocketResponse{}
+	mi := &file_types_host_call_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostSocketResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostSocketResponse) ProtoMessage() {}
+
+func (x *HostSocketResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostSocketResponse.ProtoReflect.Descriptor instead.
+func (*HostSocketResponse) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HostSocketResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *HostSocketResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *HostSocketResponse) GetFd() int32 {
+	if x != nil {
+		return x.Fd
+	}
+	return 0
+}
+
+func (x *HostSocketResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *HostSocketResponse) GetBytesRead() int32 {
+	if x != nil {
+		return x.BytesRead
+	}
+	return 0
+}
+
+func (x *HostSocketResponse) GetBytesSent() int32 {
+	if x != nil {
+		return x.BytesSent
+	}
+	return 0
+}
+
+func (x *HostSocketResponse) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *HostSocketResponse) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+type HostKVKeys struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []string               `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostKVKeys) Reset() {
+	*x = HostKVKeys{}
+	mi := &file_types_host_call_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostKVKeys) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostKVKeys) ProtoMessage() {}
+
+func (x *HostKVKeys) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostKVKeys.ProtoReflect.Descriptor instead.
+func (*HostKVKeys) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HostKVKeys) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type HostLogRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Fields        []*HostLogField        `protobuf:"bytes,3,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostLogRecord) Reset() {
+	*x = HostLogRecord{}
+	mi := &file_types_host_call_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostLogRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostLogRecord) ProtoMessage() {}
+
+func (x *HostLogRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostLogRecord.ProtoReflect.Descriptor instead.
+func (*HostLogRecord) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HostLogRecord) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *HostLogRecord) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *HostLogRecord) GetFields() []*HostLogField {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+type HostLogField struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostLogField) Reset() {
+	*x = HostLogField{}
+	mi := &file_types_host_call_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostLogField) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostLogField) ProtoMessage() {}
+
+func (x *HostLogField) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostLogField.ProtoReflect.Descriptor instead.
+func (*HostLogField) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *HostLogField) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HostLogField) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type HostMetric struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Value         float64                `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
+	Labels        []*HostMetricLabel     `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty"`
+	Delta         bool                   `protobuf:"varint,5,opt,name=delta,proto3" json:"delta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostMetric) Reset() {
+	*x = HostMetric{}
+	mi := &file_types_host_call_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostMetric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostMetric) ProtoMessage() {}
+
+func (x *HostMetric) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostMetric.ProtoReflect.Descriptor instead.
+func (*HostMetric) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HostMetric) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *HostMetric) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *HostMetric) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *HostMetric) GetLabels() []*HostMetricLabel {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *HostMetric) GetDelta() bool {
+	if x != nil {
+		return x.Delta
+	}
+	return false
+}
+
+type HostMetricLabel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostMetricLabel) Reset() {
+	*x = HostMetricLabel{}
+	mi := &file_types_host_call_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostMetricLabel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostMetricLabel) ProtoMessage() {}
+
+func (x *HostMetricLabel) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostMetricLabel.ProtoReflect.Descriptor instead.
+func (*HostMetricLabel) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HostMetricLabel) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HostMetricLabel) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type HostInvokeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Request       *FDRequest             `protobuf:"bytes,2,opt,name=request,proto3" json:"request,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostInvokeRequest) Reset() {
+	*x = HostInvokeRequest{}
+	mi := &file_types_host_call_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostInvokeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostInvokeRequest) ProtoMessage() {}
+
+func (x *HostInvokeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostInvokeRequest.ProtoReflect.Descriptor instead.
+func (*HostInvokeRequest) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HostInvokeRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *HostInvokeRequest) GetRequest() *FDRequest {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+type HostInvokeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Response      *FDResponse            `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostInvokeResponse) Reset() {
+	*x = HostInvokeResponse{}
+	mi := &file_types_host_call_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostInvokeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostInvokeResponse) ProtoMessage() {}
+
+func (x *HostInvokeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_types_host_call_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostInvokeResponse.ProtoReflect.Descriptor instead.
+func (*HostInvokeResponse) Descriptor() ([]byte, []int) {
+	return file_types_host_call_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *HostInvokeResponse) GetResponse() *FDResponse {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *HostInvokeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *HostInvokeResponse) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+var File_types_host_call_proto protoreflect.FileDescriptor
+
+const file_types_host_call_proto_rawDesc = "" +
+	"\n" +
+	"\x15types/host_call.proto\x12\x05types\x1a\x13types/fd_http.proto\"\xdf\x01\n" +
+	"\x0fHostHTTPRequest\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12=\n" +
+	"\aheaders\x18\x03 \x03(\v2#.types.HostHTTPRequest.HeadersEntryR\aheaders\x12\x12\n" +
+	"\x04body\x18\x04 \x01(\fR\x04body\x1aO\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x05value\x18\x02 \x01(\v2\x13.types.HeaderFieldsR\x05value:\x028\x01\"\x82\x02\n" +
+	"\x10HostHTTPResponse\x12\x1f\n" +
+	"\vstatus_code\x18\x01 \x01(\x05R\n" +
+	"statusCode\x12>\n" +
+	"\aheaders\x18\x02 \x03(\v2$.types.HostHTTPResponse.HeadersEntryR\aheaders\x12\x12\n" +
+	"\x04body\x18\x03 \x01(\fR\x04body\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x12\n" +
+	"\x04code\x18\x05 \x01(\tR\x04code\x1aO\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x05value\x18\x02 \x01(\v2\x13.types.HeaderFieldsR\x05value:\x028\x01\"\xbc\x01\n" +
+	"\x11HostSocketRequest\x12\x1c\n" +
+	"\toperation\x18\x01 \x01(\tR\toperation\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x0e\n" +
+	"\x02fd\x18\x03 \x01(\x05R\x02fd\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x05R\x04size\x12\x18\n" +
+	"\anetwork\x18\x06 \x01(\tR\anetwork\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\a \x01(\x05R\ttimeoutMs\"\xd8\x01\n" +
+	"\x12HostSocketResponse\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x0e\n" +
+	"\x02fd\x18\x03 \x01(\x05R\x02fd\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12\x1d\n" +
+	"\n" +
+	"bytes_read\x18\x05 \x01(\x05R\tbytesRead\x12\x1d\n" +
+	"\n" +
+	"bytes_sent\x18\x06 \x01(\x05R\tbytesSent\x12\x18\n" +
+	"\aaddress\x18\a \x01(\tR\aaddress\x12\x1c\n" +
+	"\taddresses\x18\b \x03(\tR\taddresses\" \n" +
+	"\n" +
+	"HostKVKeys\x12\x12\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys\"l\n" +
+	"\rHostLogRecord\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12+\n" +
+	"\x06fields\x18\x03 \x03(\v2\x13.types.HostLogFieldR\x06fields\"6\n" +
+	"\fHostLogField\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\x90\x01\n" +
+	"\n" +
+	"HostMetric\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x01R\x05value\x12.\n" +
+	"\x06labels\x18\x04 \x03(\v2\x16.types.HostMetricLabelR\x06labels\x12\x14\n" +
+	"\x05delta\x18\x05 \x01(\bR\x05delta\"9\n" +
+	"\x0fHostMetricLabel\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"W\n" +
+	"\x11HostInvokeRequest\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12*\n" +
+	"\arequest\x18\x02 \x01(\v2\x10.types.FDRequestR\arequest\"m\n" +
+	"\x12HostInvokeResponse\x12-\n" +
+	"\bresponse\x18\x01 \x01(\v2\x11.types.FDResponseR\bresponse\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04codeB\x16Z\x14ignis-wasmtime/typesb\x06proto3"
+
+var (
+	file_types_host_call_proto_rawDescOnce sync.Once
+	file_types_host_call_proto_rawDescData []byte
+)
+
+func file_types_host_call_proto_rawDescGZIP() []byte {
+	file_types_host_call_proto_rawDescOnce.Do(func() {
+		file_types_host_call_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_types_host_call_proto_rawDesc), len(file_types_host_call_proto_rawDesc)))
+	})
+	return file_types_host_call_proto_rawDescData
+}
+
+var file_types_host_call_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_types_host_call_proto_goTypes = []any{
+	(*HostHTTPRequest)(nil),    // 0: types.HostHTTPRequest
+	(*HostHTTPResponse)(nil),   // 1: types.HostHTTPResponse
+	(*HostSocketRequest)(nil),  // 2: types.HostSocketRequest
+	(*HostSocketResponse)(nil), // 3: types.HostSocketResponse
+	(*HostKVKeys)(nil),         // 4: types.HostKVKeys
+	(*HostLogRecord)(nil),      // 5: types.HostLogRecord
+	(*HostLogField)(nil),       // 6: types.HostLogField
+	(*HostMetric)(nil),         // 7: types.HostMetric
+	(*HostMetricLabel)(nil),    // 8: types.HostMetricLabel
+	(*HostInvokeRequest)(nil),  // 9: types.HostInvokeRequest
+	(*HostInvokeResponse)(nil), // 10: types.HostInvokeResponse
+	nil,                        // 11: types.HostHTTPRequest.HeadersEntry
+	nil,                        // 12: types.HostHTTPResponse.HeadersEntry
+	(*FDRequest)(nil),          // 13: types.FDRequest
+	(*FDResponse)(nil),         // 14: types.FDResponse
+	(*HeaderFields)(nil),       // 15: types.HeaderFields
+}
+var file_types_host_call_proto_depIdxs = []int32{
+	11, // 0: types.HostHTTPRequest.headers:type_name -> types.HostHTTPRequest.HeadersEntry
+	12, // 1: types.HostHTTPResponse.headers:type_name -> types.HostHTTPResponse.HeadersEntry
+	6,  // 2: types.HostLogRecord.fields:type_name -> types.HostLogField
+	8,  // 3: types.HostMetric.labels:type_name -> types.HostMetricLabel
+	13, // 4: types.HostInvokeRequest.request:type_name -> types.FDRequest
+	14, // 5: types.HostInvokeResponse.response:type_name -> types.FDResponse
+	15, // 6: types.HostHTTPRequest.HeadersEntry.value:type_name -> types.HeaderFields
+	15, // 7: types.HostHTTPResponse.HeadersEntry.value:type_name -> types.HeaderFields
+	8,  // [8:8] is the sub-list for method output_type
+	8,  // [8:8] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_types_host_call_proto_init() }
+func file_types_host_call_proto_init() {
+	if File_types_host_call_proto != nil {
+		return
+	}
+	file_types_fd_http_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_types_host_call_proto_rawDesc), len(file_types_host_call_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_types_host_call_proto_goTypes,
+		DependencyIndexes: file_types_host_call_proto_depIdxs,
+		MessageInfos:      file_types_host_call_proto_msgTypes,
+	}.Build()
+	File_types_host_call_proto = out.File
+	file_types_host_call_proto_goTypes = nil
+	file_types_host_call_proto_depIdxs = nil
+}